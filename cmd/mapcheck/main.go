@@ -0,0 +1,60 @@
+// mapcheck loads every .map file and world.txt and reports problems with them,
+// it exits with status 1 if any errors are found so it can gate map changes
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/gorillana/rpg/game"
+)
+
+func main() {
+	mapsDir := flag.String("maps", "game/maps", "directory holding the .map files and world.txt")
+	atlasFile := flag.String("atlas", "ui2d/assets/atlas-index.txt", "ui sprite index every monster needs an entry in, empty to skip")
+	strict := flag.Bool("strict", false, "treat warnings as errors")
+	flag.Parse()
+
+	var atlas io.Reader
+	if *atlasFile != "" {
+		file, err := os.Open(*atlasFile)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		// left open, the process ends right after
+		atlas = file
+	}
+	code := check(os.Stdout, os.DirFS(*mapsDir), atlas, *atlasFile, *strict)
+	os.Exit(code)
+}
+
+// check prints every problem with maps, and with atlas unless it's nil, and is the
+// status to exit with: 1 when there are errors, warnings only count with strict
+func check(out io.Writer, maps fs.FS, atlas io.Reader, atlasName string, strict bool) int {
+	problems := game.CheckMaps(maps)
+	if atlas != nil {
+		problems = append(problems, game.CheckAtlas(maps, atlas, atlasName)...)
+	}
+
+	errorCount := 0
+	warningCount := 0
+	for _, p := range problems {
+		fmt.Fprintln(out, p)
+		if p.Warning && !strict {
+			warningCount++
+		} else {
+			errorCount++
+		}
+	}
+
+	if errorCount > 0 {
+		fmt.Fprintf(out, "%d errors, %d warnings\n", errorCount, warningCount)
+		return 1
+	}
+	fmt.Fprintf(out, "maps ok, %d warnings\n", warningCount)
+	return 0
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func maps(world string) fstest.MapFS {
+	return fstest.MapFS{
+		"world.txt": {Data: []byte(world)},
+		"room.map":  {Data: []byte("#####\n#@..#\n#####\n")},
+		"hall.map":  {Data: []byte("####\n#..#\n####\n")},
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	const atlas = "B 0,0,1\nS 0,0,1\nD 0,0,1\n"
+	tests := []struct {
+		name   string
+		world  string
+		atlas  string
+		strict bool
+		code   int
+		// the last line printed
+		summary string
+	}{
+		{"good", "room\nroom,3,1,hall,1,1\nhall,2,1,room,2,1", "", false, 0, "maps ok, 0 warnings"},
+		{"warnings pass", "room\nroom,3,1,hall,1,1", "", false, 0, "maps ok, 1 warnings"},
+		{"warnings fail with strict", "room\nroom,3,1,hall,1,1", "", true, 1, "1 errors, 0 warnings"},
+		{"errors fail", "room\nroom,3,1,cellar,1,1", "", false, 1, "2 errors, 0 warnings"},
+		{"missing sprites fail", "room\nroom,3,1,hall,1,1\nhall,2,1,room,2,1", atlas, false, 1, "errors, 0 warnings"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		var code int
+		if tt.atlas == "" {
+			code = check(&out, maps(tt.world), nil, "", tt.strict)
+		} else {
+			code = check(&out, maps(tt.world), strings.NewReader(tt.atlas), "atlas", tt.strict)
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if code != tt.code || !strings.HasSuffix(lines[len(lines)-1], tt.summary) {
+			t.Errorf("%s: exit %d with %q, want %d and %q", tt.name, code, out.String(), tt.code, tt.summary)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Package dungeon builds random levels out of rooms and corridors.
+// Everything comes from one seeded rand so a seed can be shared to replay a level
+package dungeon
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/gorillana/rpg/game"
+	"github.com/gorillana/rpg/pathfinding"
+)
+
+// room sizes include the walls
+const (
+	minRoomSize = 5
+	maxRoomSize = 12
+	// smallest level that still fits a room
+	minLevelSize = 8
+)
+
+type room struct {
+	x, y, w, h int
+}
+
+func (r room) center() game.Pos {
+	return game.Pos{X: r.x + r.w/2, Y: r.y + r.h/2}
+}
+
+// rooms need a gap of one tile so their walls never merge
+func (r room) overlaps(o room) bool {
+	return r.x-1 < o.x+o.w && o.x-1 < r.x+r.w && r.y-1 < o.y+o.h && o.y-1 < r.y+r.h
+}
+
+// random tile inside the walls of the room
+func (r room) randomFloor(rng *rand.Rand) game.Pos {
+	return game.Pos{X: r.x + 1 + rng.Intn(r.w-2), Y: r.y + 1 + rng.Intn(r.h-2)}
+}
+
+func randomSize(rng *rand.Rand, limit int) int {
+	max := maxRoomSize
+	if limit < max {
+		max = limit
+	}
+	return minRoomSize + rng.Intn(max-minRoomSize+1)
+}
+
+func placeRooms(rng *rand.Rand, width, height int) []room {
+	rooms := make([]room, 0)
+	attempts := width * height / 20
+	for i := 0; i < attempts; i++ {
+		w := randomSize(rng, width)
+		h := randomSize(rng, height)
+		r := room{rng.Intn(width - w + 1), rng.Intn(height - h + 1), w, h}
+
+		fits := true
+		for _, other := range rooms {
+			if r.overlaps(other) {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			rooms = append(rooms, r)
+		}
+	}
+
+	// joining rooms left to right keeps corridors from criss crossing the whole map
+	sort.SliceStable(rooms, func(i, j int) bool {
+		ci, cj := rooms[i].center(), rooms[j].center()
+		if ci.X != cj.X {
+			return ci.X < cj.X
+		}
+		return ci.Y < cj.Y
+	})
+	return rooms
+}
+
+func carveRoom(level *game.Level, r room) {
+	for y := r.y; y < r.y+r.h; y++ {
+		for x := r.x; x < r.x+r.w; x++ {
+			if x == r.x || y == r.y || x == r.x+r.w-1 || y == r.y+r.h-1 {
+				level.Map[y][x].Rune = game.StoneWall
+			} else {
+				level.Map[y][x].Rune = game.DirtFloor
+			}
+		}
+	}
+}
+
+// digs an L shaped corridor, returns the room walls it broke through so doors can go there
+func carveCorridor(level *game.Level, rng *rand.Rand, from, to game.Pos) []game.Pos {
+	broken := make([]game.Pos, 0)
+	dig := func(x, y int) {
+		t := &level.Map[y][x]
+		if t.Rune == game.StoneWall {
+			broken = append(broken, game.Pos{X: x, Y: y})
+		}
+		t.Rune = game.DirtFloor
+	}
+
+	step := func(a, b int) int {
+		if a < b {
+			return 1
+		}
+		return -1
+	}
+
+	x, y := from.X, from.Y
+	if rng.Intn(2) == 0 {
+		for ; x != to.X; x += step(x, to.X) {
+			dig(x, y)
+		}
+		for ; y != to.Y; y += step(y, to.Y) {
+			dig(x, y)
+		}
+	} else {
+		for ; y != to.Y; y += step(y, to.Y) {
+			dig(x, y)
+		}
+		for ; x != to.X; x += step(x, to.X) {
+			dig(x, y)
+		}
+	}
+	dig(x, y)
+	return broken
+}
+
+func isRune(level *game.Level, x, y int, r rune) bool {
+	if y < 0 || y >= len(level.Map) || x < 0 || x >= len(level.Map[y]) {
+		return false
+	}
+	return level.Map[y][x].Rune == r
+}
+
+// puts walls around every corridor so no floor touches empty space
+func wallIn(level *game.Level) {
+	for y, row := range level.Map {
+		for x, t := range row {
+			if t.Rune != game.DirtFloor {
+				continue
+			}
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if isRune(level, x+dx, y+dy, game.Blank) {
+						level.Map[y+dy][x+dx].Rune = game.StoneWall
+					}
+				}
+			}
+		}
+	}
+}
+
+// a broken wall becomes a door only if it is a real doorway, walls on two opposite sides
+func addDoors(level *game.Level, broken []game.Pos) {
+	for _, pos := range broken {
+		x, y := pos.X, pos.Y
+		horizontal := isRune(level, x, y-1, game.StoneWall) && isRune(level, x, y+1, game.StoneWall) &&
+			isRune(level, x-1, y, game.DirtFloor) && isRune(level, x+1, y, game.DirtFloor)
+		vertical := isRune(level, x-1, y, game.StoneWall) && isRune(level, x+1, y, game.StoneWall) &&
+			isRune(level, x, y-1, game.DirtFloor) && isRune(level, x, y+1, game.DirtFloor)
+		if horizontal || vertical {
+			level.Map[y][x].OverlayRune = game.CloseDoor
+		}
+	}
+}
+
+// the room whose center is the longest walk from start, the first of them on a tie
+func furthestRoom(level *game.Level, rooms []room, start game.Pos) room {
+	dist := pathfinding.Dijkstra(game.FloorGraph(level), start)
+	best, bestDist := rooms[0], -1
+	for _, r := range rooms {
+		if d, ok := dist[r.center()]; ok && d > bestDist {
+			best, bestDist = r, d
+		}
+	}
+	return best
+}
+
+func isFree(level *game.Level, pos game.Pos) bool {
+	if pos == level.Player.Pos || level.Map[pos.Y][pos.X].OverlayRune != game.Blank {
+		return false
+	}
+	_, exists := level.Monsters[pos]
+	return !exists && len(level.Items[pos]) == 0
+}
+
+func populate(level *game.Level, rng *rand.Rand, r room) {
+	monsterCount := rng.Intn(3)
+	for i := 0; i < monsterCount; i++ {
+		pos := r.randomFloor(rng)
+		if !isFree(level, pos) {
+			continue
+		}
+		roll := rng.Intn(10)
+		switch {
+		case roll < 5:
+			level.Monsters[pos] = game.NewBat(pos)
+		case roll < 9:
+			level.Monsters[pos] = game.NewSpider(pos)
+		default:
+			level.Monsters[pos] = game.NewDragon(pos)
+		}
+	}
+
+	if rng.Intn(4) == 0 {
+		pos := r.randomFloor(rng)
+		if isFree(level, pos) {
+			switch rng.Intn(3) {
+			case 0:
+				level.Items[pos] = append(level.Items[pos], game.NewSword(pos))
+			case 1:
+				level.Items[pos] = append(level.Items[pos], game.NewHelmet(pos))
+			default:
+				level.Items[pos] = append(level.Items[pos], game.NewPotion(pos))
+			}
+		}
+	}
+}
+
+// Generate builds a width by height level of rooms joined by corridors. The same
+// seed and size always give the same level. Levels smaller than 8x8 are grown to fit a room
+func Generate(seed int64, width, height int) *game.Level {
+	if width < minLevelSize {
+		width = minLevelSize
+	}
+	if height < minLevelSize {
+		height = minLevelSize
+	}
+	rng := rand.New(rand.NewSource(seed))
+	level := game.NewLevel("dungeon-"+strconv.FormatInt(seed, 10), width, height, game.NewPlayer())
+	level.Seed(seed)
+
+	rooms := placeRooms(rng, width, height)
+	for _, r := range rooms {
+		carveRoom(level, r)
+	}
+	broken := make([]game.Pos, 0)
+	for i := 1; i < len(rooms); i++ {
+		broken = append(broken, carveCorridor(level, rng, rooms[i-1].center(), rooms[i].center())...)
+	}
+	wallIn(level)
+	addDoors(level, broken)
+
+	// the player starts next to the way up, the way down is in the room the longest walk away
+	first := rooms[0]
+	level.Player.Pos = first.center()
+	up := game.Pos{X: first.x + 1, Y: first.y + 1}
+	if up != level.Player.Pos {
+		level.Map[up.Y][up.X].OverlayRune = game.UpStair
+	}
+	if len(rooms) > 1 {
+		down := furthestRoom(level, rooms[1:], level.Player.Pos).center()
+		level.Map[down.Y][down.X].OverlayRune = game.DownStair
+	}
+
+	for _, r := range rooms[1:] {
+		populate(level, rng, r)
+	}
+	return level
+}
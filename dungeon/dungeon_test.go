@@ -0,0 +1,48 @@
+package dungeon
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/gorillana/rpg/game"
+)
+
+var seeds = []int64{1, 42, -7, 99999}
+
+// dump is everything about a level a player could see: tiles, monsters, items and where
+// the player starts
+func dump(t *testing.T, level *game.Level) []byte {
+	t.Helper()
+	data, err := json.Marshal(game.NewSnapshot(level))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// The same seed always gives the same dungeon, down to the byte
+func TestGenerateDeterministic(t *testing.T) {
+	for _, seed := range seeds {
+		first := dump(t, Generate(seed, 80, 50))
+		for i := 0; i < 5; i++ {
+			if again := dump(t, Generate(seed, 80, 50)); !bytes.Equal(first, again) {
+				t.Fatalf("seed %d: generation %d differs from the first", seed, i+2)
+			}
+		}
+	}
+}
+
+func TestGenerateSeedsDiffer(t *testing.T) {
+	levels := make(map[string]int64)
+	for _, seed := range seeds {
+		level := Generate(seed, 80, 50)
+		// the name has the seed in it, take it out so only the dungeon itself is compared
+		level.Name = ""
+		key := string(dump(t, level))
+		if other, ok := levels[key]; ok {
+			t.Errorf("seeds %d and %d gave the same dungeon", other, seed)
+		}
+		levels[key] = seed
+	}
+}
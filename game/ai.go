@@ -0,0 +1,166 @@
+package game
+
+// AIState is what a monster is busy doing, it's decided again at the start of every Update
+type AIState int
+
+const (
+	// standing still until it sees the player
+	Idle AIState = iota
+	// drifting around at random until it sees the player
+	Wandering
+	// the player is in sight, go for them
+	Chasing
+	// lost sight of the player, heading to where they were last seen
+	Searching
+	// hurt badly enough to run while the player is in sight
+	Fleeing
+)
+
+// Behavior is the per-monster-type part of the ai, set from monsters.txt
+type Behavior struct {
+	// percent chance each turn that a monster with nothing to chase moves about
+	WanderChance int
+	// runs once its hitpoints fall to this percent of its max, 0 never runs
+	FleeAt int
+	// how many turns it looks around where the player was last seen before giving up
+	SearchTurns int
+	// chases and searches through closed doors, opening them on the way
+	OpensDoors bool
+}
+
+var defaultBehavior = Behavior{WanderChance: 25, FleeAt: 0, SearchTurns: 5}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// canSee is whether someone at from could see to within sightRange, it's the level's FOV
+// so the line is there both ways, but it ignores light and only the player needs that.
+// Walls, closed doors and deep grass block sight, monsters don't
+func (level *Level) canSee(from, to Pos, sightRange int) bool {
+	return level.fov().Sees(level, from, to, sightRange)
+}
+
+// the free tiles m could step to from pos, never the player's
+func (m *Monster) freeNeighbors(level *Level, pos Pos) []Pos {
+	free := make([]Pos, 0, 8)
+	for _, next := range neighborsWhere(level, pos, m.walkable) {
+		if next != level.Player.Pos {
+			free = append(free, next)
+		}
+	}
+	return free
+}
+
+func (m *Monster) hurtEnoughToFlee() bool {
+	return m.Behavior.FleeAt > 0 && m.Hitpoints*100 <= m.Stats().MaxHitpoints*m.Behavior.FleeAt
+}
+
+// think looks for the player and picks the monster's state for this turn
+func (m *Monster) think(level *Level) {
+	player := level.Player
+	seesPlayer := level.canSee(m.Pos, player.Pos, m.Stats().SightRange)
+	// only tell the player about monsters they can see
+	visible := level.Map[m.Pos.Y][m.Pos.X].Visible
+	before := m.State
+
+	switch {
+	case seesPlayer && m.hurtEnoughToFlee():
+		m.State = Fleeing
+	case seesPlayer:
+		m.State = Chasing
+	case m.State == Chasing || m.State == Fleeing:
+		m.State = Searching
+		m.searchLeft = m.Behavior.SearchTurns
+	case m.State == Searching && m.searchLeft <= 0:
+		m.State = Idle
+	case m.State == Idle || m.State == Wandering:
+		m.State = Idle
+		if level.rng.Intn(100) < m.Behavior.WanderChance {
+			m.State = Wandering
+		}
+	}
+	if seesPlayer {
+		m.LastSeen = player.Pos
+	}
+
+	if visible && m.State != before {
+		switch m.State {
+		case Chasing:
+			if before != Searching {
+				level.message(m.Name + " spots " + player.Name)
+			}
+		case Fleeing:
+			level.message(m.Name + " turns to run")
+		}
+	}
+}
+
+// plans up to steps moves for the current state, the first entry is where the monster stands
+func (m *Monster) plan(level *Level, steps int) []Pos {
+	switch m.State {
+	case Chasing:
+		return level.PathToPlayer(m, steps)
+	case Searching:
+		if m.Pos != m.LastSeen {
+			path := level.aStar(m.Pos, m.LastSeen, m.mover().walks)
+			if len(path) > 1 {
+				return path
+			}
+		}
+		// got there or can't, poke around nearby until it gives up
+		m.searchLeft--
+		return m.wanderPath(level, steps)
+	case Fleeing:
+		path := m.fleePath(level, steps)
+		// cornered next to the player, so fight
+		if len(path) < 2 && level.canStep(m.Pos, level.Player.Pos) {
+			return []Pos{m.Pos, level.Player.Pos}
+		}
+		return path
+	case Wandering:
+		return m.wanderPath(level, steps)
+	}
+	return nil
+}
+
+func (m *Monster) wanderPath(level *Level, steps int) []Pos {
+	path := []Pos{m.Pos}
+	pos := m.Pos
+	for i := 0; i < steps; i++ {
+		free := m.freeNeighbors(level, pos)
+		if len(free) == 0 {
+			break
+		}
+		pos = free[level.rng.Intn(len(free))]
+		path = append(path, pos)
+	}
+	return path
+}
+
+// each step takes it further from the player, it stops when nothing is further
+func (m *Monster) fleePath(level *Level, steps int) []Pos {
+	path := []Pos{m.Pos}
+	pos := m.Pos
+	player := level.Player.Pos
+	distance := func(p Pos) int {
+		return (p.X-player.X)*(p.X-player.X) + (p.Y-player.Y)*(p.Y-player.Y)
+	}
+	for i := 0; i < steps; i++ {
+		best, bestDistance := pos, distance(pos)
+		for _, next := range m.freeNeighbors(level, pos) {
+			if distance(next) > bestDistance {
+				best, bestDistance = next, distance(next)
+			}
+		}
+		if best == pos {
+			break
+		}
+		pos = best
+		path = append(path, pos)
+	}
+	return path
+}
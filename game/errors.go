@@ -0,0 +1,26 @@
+package game
+
+import "fmt"
+
+// ErrUnknownGlyph is returned when a map file has a character the loader doesn't know.
+// Line and Col start at 1 so they match what an editor shows
+type ErrUnknownGlyph struct {
+	File string
+	Line int
+	Col  int
+	Rune rune
+}
+
+func (e *ErrUnknownGlyph) Error() string {
+	return fmt.Sprintf("%s:%d:%d: unknown map character %q", e.File, e.Line, e.Col, e.Rune)
+}
+
+// ErrUnknownLevel is returned when world.txt names a level there is no .map file for
+type ErrUnknownLevel struct {
+	Name      string
+	WorldLine int
+}
+
+func (e *ErrUnknownLevel) Error() string {
+	return fmt.Sprintf("world.txt:%d: no level named %q, expected a %s.map file", e.WorldLine, e.Name, e.Name)
+}
@@ -0,0 +1,108 @@
+package game
+
+import "strconv"
+
+// GameEvent is the kind of an Event
+type GameEvent int
+
+const (
+	// the player stepped onto Pos
+	Move GameEvent = iota
+	// Actor opened the door at Pos
+	DoorOpen
+	// Actor closed the door at Pos
+	DoorClose
+	// Actor hit Target for Amount
+	Hit
+	// Actor swung at Target and missed
+	Miss
+	// Actor hit Target twice as hard, for Amount
+	Crit
+	// Actor's blow finished off Target
+	Kill
+	// Actor went through a portal to the level named Target, arriving at Pos
+	Portal
+	// the item events all have the item's name in Target
+	Pickup
+	Drop
+	Equip
+	Unequip
+	// Actor drank Target and got back Amount hitpoints
+	Healed
+	// Actor drank Target and gained Amount strength
+	Strengthened
+	// Actor took Amount damage from the lava at Pos
+	Burned
+	// the player died, the ui should show its death screen
+	Death
+	// anything else worth telling the player, the text is in Target
+	Message
+)
+
+// Event is one thing that happened during a turn
+type Event struct {
+	Kind   GameEvent
+	Actor  string
+	Target string
+	Pos    Pos
+	Amount int
+}
+
+// String is the event's line in the Events log, empty for events that aren't logged
+func (e Event) String() string {
+	switch e.Kind {
+	case Hit:
+		return e.Actor + " hit " + e.Target + " for " + strconv.Itoa(e.Amount)
+	case Miss:
+		return e.Actor + " missed " + e.Target
+	case Crit:
+		return e.Actor + " critically hit " + e.Target + " for " + strconv.Itoa(e.Amount)
+	case Kill:
+		return e.Actor + " killed " + e.Target
+	case Portal:
+		return e.Actor + " went to " + e.Target
+	case Pickup:
+		return e.Actor + " picked up: " + e.Target
+	case Drop:
+		return e.Actor + " dropped: " + e.Target
+	case Equip:
+		return e.Actor + " equipped " + e.Target
+	case Unequip:
+		return e.Actor + " took off " + e.Target
+	case Healed:
+		return e.Actor + " drank " + e.Target + " and healed " + strconv.Itoa(e.Amount)
+	case Strengthened:
+		return e.Actor + " drank " + e.Target + ", strength +" + strconv.Itoa(e.Amount)
+	case Burned:
+		return e.Actor + " was burned for " + strconv.Itoa(e.Amount)
+	case Death:
+		return "You have died"
+	case Message:
+		return e.Target
+	}
+	return ""
+}
+
+// records an event for this turn and writes its line to the log
+func (level *Level) emit(e Event) {
+	level.TurnEvents = append(level.TurnEvents, e)
+	text := e.String()
+	if text != "" {
+		level.AddEvent(text)
+	}
+}
+
+// tells the player something that isn't tied to anyone in the level
+func (level *Level) message(text string) {
+	level.emit(Event{Kind: Message, Target: text, Pos: level.Player.Pos})
+}
+
+// Happened reports whether an event of the given kind was emitted this turn
+func (level *Level) Happened(kind GameEvent) bool {
+	for _, e := range level.TurnEvents {
+		if e.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package game
+
+import "github.com/gorillana/rpg/pathfinding"
+
+// flowField is how far every tile is from the player by walking, in stepCost units,
+// found with one Dijkstra search out from the player and shared by every monster chasing
+// them. Monsters aren't part of it, a monster steps around the others when it follows the
+// field, so it stays good while they move and only has to be worked out again when the
+// player moves or a door opens or shuts. Each way of getting about, swimming or opening
+// doors or neither, gets a field of its own
+type flowField struct {
+	goal Pos
+	dist map[Pos]int
+}
+
+// the distance of tiles the player can't be reached from
+const unreachable = -1
+
+// makes the next monster that needs the field work it out again
+func (level *Level) invalidateFlow() {
+	level.flows = nil
+}
+
+func (level *Level) flowToPlayer(mv mover) *flowField {
+	goal := level.Player.Pos
+	if level.flows == nil {
+		level.flows = make(map[mover]*flowField)
+	}
+	field := level.flows[mv]
+	if field == nil || field.goal != goal {
+		field = newFlowField(level, goal, mv.passes)
+		level.flows[mv] = field
+	}
+	return field
+}
+
+func newFlowField(level *Level, goal Pos, pass func(*Level, Pos) bool) *flowField {
+	if !inRange(level, goal) {
+		return &flowField{goal, map[Pos]int{}}
+	}
+	// monsters walk this the other way, from each tile towards the goal
+	return &flowField{goal, pathfinding.Dijkstra[Pos](reverseGraph{levelGraph{level, pass}}, goal)}
+}
+
+func (field *flowField) at(pos Pos) int {
+	d, ok := field.dist[pos]
+	if !ok {
+		return unreachable
+	}
+	return d
+}
+
+// PathToPlayer follows the flow field downhill from m for up to steps moves, going
+// around monsters in the way, through water only for swimmers and closed doors only for
+// monsters that open them. The first entry is m's tile, the last is the player's once
+// it's reached. It's just m's tile when there's no way closer
+func (level *Level) PathToPlayer(m *Monster, steps int) []Pos {
+	mv := m.mover()
+	field := level.flowToPlayer(mv)
+	path := []Pos{m.Pos}
+	pos := m.Pos
+	for i := 0; i < steps && pos != field.goal; i++ {
+		here := field.at(pos)
+		if here == unreachable {
+			break
+		}
+		// the cheapest way on is the step plus what's left from where it lands, not the
+		// nearest tile, wading into water to get one tile closer can cost more than the
+		// way around. Only tiles closer than this one count, so it never goes in circles
+		best, bestCost := pos, 0
+		for _, next := range neighborsWhere(level, pos, mv.passes) {
+			d := field.at(next)
+			if _, taken := level.Monsters[next]; taken || d == unreachable || d >= here {
+				continue
+			}
+			cost := stepCost(level, pos, next) + d
+			if best == pos || cost < bestCost {
+				best, bestCost = next, cost
+			}
+		}
+		if best == pos {
+			break
+		}
+		pos = best
+		path = append(path, pos)
+	}
+	return path
+}
+
+// FindPath is the shortest way from one tile to another with A*, walking around monsters.
+// Use PathToPlayer for monsters going after the player, it shares its work between them
+func (level *Level) FindPath(from, to Pos) []Pos {
+	return level.aStar(from, to, canWalk)
+}
@@ -0,0 +1,104 @@
+package game
+
+import "testing"
+
+// drawnLevel is a level drawn the way map files are, only the terrain, P is the player
+func drawnLevel(rows ...string) *Level {
+	level := NewLevel("drawn", len(rows[0]), len(rows), NewPlayer())
+	for y, row := range rows {
+		for x, c := range row {
+			t := Tile{Rune: DirtFloor, OverlayRune: Blank}
+			switch c {
+			case '#':
+				t.Rune = StoneWall
+			case '~':
+				t.Rune = Water
+			case '"':
+				t.Rune = DeepGrass
+			case '|':
+				t.OverlayRune = CloseDoor
+			case '/':
+				t.OverlayRune = OpenDoor
+			case 'P':
+				level.Player.Pos = Pos{x, y}
+			}
+			level.Map[y][x] = t
+		}
+	}
+	level.Seed(1)
+	return level
+}
+
+func pathCost(level *Level, path []Pos) int {
+	cost := 0
+	for i := 1; i < len(path); i++ {
+		cost += stepCost(level, path[i-1], path[i])
+	}
+	return cost
+}
+
+// Following the flow field costs as much as an A* search for the same monster, from
+// everywhere it can get to the player. Where every step costs the same that means as
+// many steps, through water a cheaper way can take more of them
+func TestPathToPlayerMatchesAStar(t *testing.T) {
+	rows := []string{
+		"##############",
+		"#....#.......#",
+		`#.~~.#.."""".#`,
+		"#.~~./...#...#",
+		"#....#...#.P.#",
+		"###|###..#...#",
+		"#.....#......#",
+		"#.~~~.|..~~~.#",
+		"##############",
+	}
+	movers := []struct {
+		name  string
+		mover mover
+	}{
+		{"walker", mover{}},
+		{"swimmer", mover{swims: true}},
+		{"door opener", mover{opensDoors: true}},
+	}
+	for _, diagonal := range []bool{false, true} {
+		for _, mv := range movers {
+			level := drawnLevel(rows...)
+			level.Diagonal = diagonal
+			goal := level.Player.Pos
+			reached := 0
+			for y, row := range level.Map {
+				for x := range row {
+					pos := Pos{x, y}
+					if pos == goal || !mv.mover.passes(level, pos) {
+						continue
+					}
+					m := testMonster(level, pos, 1)
+					m.Swims, m.Behavior.OpensDoors = mv.mover.swims, mv.mover.opensDoors
+					flow := level.PathToPlayer(m, 1000)
+					search := level.aStar(pos, goal, mv.mover.passes)
+					delete(level.Monsters, pos)
+
+					if search == nil {
+						if flow[len(flow)-1] == goal {
+							t.Errorf("diagonal %v, %s from %v: the field reaches the player where A* doesn't", diagonal, mv.name, pos)
+						}
+						continue
+					}
+					reached++
+					if flow[len(flow)-1] != goal {
+						t.Errorf("diagonal %v, %s from %v: the field stops at %v", diagonal, mv.name, pos, flow[len(flow)-1])
+						continue
+					}
+					sameSteps := len(flow) == len(search) || mv.mover.swims || diagonal
+					if !sameSteps || pathCost(level, flow) != pathCost(level, search) {
+						t.Errorf("diagonal %v, %s from %v: field %d steps costing %d, A* %d steps costing %d", diagonal, mv.name, pos,
+							len(flow), pathCost(level, flow), len(search), pathCost(level, search))
+					}
+				}
+			}
+			if reached == 0 {
+				t.Errorf("diagonal %v, %s: nothing could reach the player", diagonal, mv.name)
+			}
+		}
+	}
+}
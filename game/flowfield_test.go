@@ -0,0 +1,101 @@
+package game_test
+
+import (
+	"testing"
+
+	"github.com/gorillana/rpg/dungeon"
+	"github.com/gorillana/rpg/game"
+)
+
+// a generated level with every monster on it and two tiles for the player to step
+// between, so each turn pays for a new flow field just like the game. The doors are
+// opened, none of the shipped monsters can open them and searches that can't get out of
+// a room don't measure anything. A monster the others have boxed in is taken off, so
+// every search in the benchmark finds the player
+func pathLevel(b *testing.B, diagonal bool) (*game.Level, []game.Pos, [2]game.Pos) {
+	b.Helper()
+	level := dungeon.Generate(1, 200, 200)
+	level.Diagonal = diagonal
+	for y := range level.Map {
+		for x := range level.Map[y] {
+			if level.Map[y][x].OverlayRune == game.CloseDoor {
+				level.Map[y][x].OverlayRune = game.OpenDoor
+			}
+		}
+	}
+	start := level.Player.Pos
+	spots := [2]game.Pos{start, start}
+	for _, p := range []game.Pos{{X: 1, Y: 0}, {X: -1, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: -1}} {
+		next := game.Pos{X: start.X + p.X, Y: start.Y + p.Y}
+		if level.Map[next.Y][next.X].Rune == game.DirtFloor && level.Monsters[next] == nil {
+			spots[1] = next
+			break
+		}
+	}
+
+	for pos := range level.Monsters {
+		if !reaches(level.FindPath(pos, start), start) {
+			delete(level.Monsters, pos)
+		}
+	}
+	monsters := make([]game.Pos, 0, len(level.Monsters))
+	for pos, m := range level.Monsters {
+		// alone on the level nobody is in the way, so following the field gets there
+		all := level.Monsters
+		level.Monsters = map[game.Pos]*game.Monster{pos: m}
+		found := reaches(level.PathToPlayer(m, len(level.Map)*len(level.Map[0])), start)
+		level.Monsters = all
+		if !found {
+			b.Fatalf("the flow field doesn't lead the %s at %v to the player", m.Name, pos)
+		}
+		monsters = append(monsters, pos)
+	}
+	if len(monsters) < 200 {
+		b.Fatalf("only %d monsters can reach the player", len(monsters))
+	}
+	return level, monsters, spots
+}
+
+func reaches(path []game.Pos, goal game.Pos) bool {
+	return len(path) > 0 && path[len(path)-1] == goal
+}
+
+var movements = []struct {
+	name     string
+	diagonal bool
+}{
+	{"straight", false},
+	{"diagonal", true},
+}
+
+// A turn of every monster on a 200x200 level running its own A* search to the player
+func BenchmarkFindPath(b *testing.B) {
+	for _, m := range movements {
+		b.Run(m.name, func(b *testing.B) {
+			level, monsters, spots := pathLevel(b, m.diagonal)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				level.Player.Pos = spots[i%2]
+				for _, pos := range monsters {
+					level.FindPath(pos, level.Player.Pos)
+				}
+			}
+		})
+	}
+}
+
+// The same turn with every monster planning two steps down the shared flow field
+func BenchmarkPathToPlayer(b *testing.B) {
+	for _, m := range movements {
+		b.Run(m.name, func(b *testing.B) {
+			level, monsters, spots := pathLevel(b, m.diagonal)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				level.Player.Pos = spots[i%2]
+				for _, pos := range monsters {
+					level.PathToPlayer(level.Monsters[pos], 2)
+				}
+			}
+		})
+	}
+}
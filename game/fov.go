@@ -0,0 +1,194 @@
+package game
+
+// FOV works out what can be seen from a tile. Walls, closed doors and deep grass block
+// sight, see canSeeThrough. Level.FOV picks the one a level uses
+type FOV interface {
+	// Compute calls see once for every tile on the map that can be seen from origin
+	// within radius, origin included
+	Compute(level *Level, origin Pos, radius int, see func(Pos))
+	// Sees is whether to can be seen from from within radius
+	Sees(level *Level, from, to Pos, radius int) bool
+}
+
+// ShadowCasting is symmetric recursive shadowcasting. Each quarter of the view is scanned
+// a row at a time outwards from the origin, and walls cut the slopes the next row is
+// scanned between. A floor tile is only seen when its center is inside the lit slopes, so
+// if one tile can see another it's seen back, and a pillar hides the same tiles from
+// both sides. Walls are seen whenever any of them is lit so rooms show their outline.
+// It never looks at a tile further than radius from the origin
+type ShadowCasting struct{}
+
+// the FOV a level uses when it doesn't say
+var defaultFOV FOV = ShadowCasting{}
+
+func (level *Level) fov() FOV {
+	if level.FOV == nil {
+		return defaultFOV
+	}
+	return level.FOV
+}
+
+func (ShadowCasting) Compute(level *Level, origin Pos, radius int, see func(Pos)) {
+	if !inRange(level, origin) {
+		return
+	}
+	see(origin)
+	inCircle := func(pos Pos) {
+		dx, dy := pos.X-origin.X, pos.Y-origin.Y
+		if dx*dx+dy*dy <= radius*radius {
+			see(pos)
+		}
+	}
+	for q := north; q <= west; q++ {
+		s := shadowcast{level, origin, q, radius, inCircle}
+		s.scan(1, slope{-1, 1}, slope{1, 1})
+	}
+}
+
+func (ShadowCasting) Sees(level *Level, from, to Pos, radius int) bool {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	if dx*dx+dy*dy > radius*radius || !inRange(level, from) || !inRange(level, to) {
+		return false
+	}
+	if from == to {
+		return true
+	}
+	// only the quarters to is in need scanning, and only as far out as it is.
+	// A tile on a diagonal is in two of them
+	seen := false
+	look := func(pos Pos) {
+		if pos == to {
+			seen = true
+		}
+	}
+	for q := north; q <= west && !seen; q++ {
+		depth, col := q.local(from, to)
+		if depth > 0 && abs(col) <= depth {
+			s := shadowcast{level, from, q, depth, look}
+			s.scan(1, slope{-1, 1}, slope{1, 1})
+		}
+	}
+	return seen
+}
+
+// quadrant is a quarter of the view, named for the way it faces
+type quadrant int
+
+const (
+	north quadrant = iota
+	east
+	south
+	west
+)
+
+// the map tile depth rows out from origin and col across
+func (q quadrant) transform(origin Pos, depth, col int) Pos {
+	switch q {
+	case north:
+		return Pos{origin.X + col, origin.Y - depth}
+	case east:
+		return Pos{origin.X + depth, origin.Y + col}
+	case south:
+		return Pos{origin.X + col, origin.Y + depth}
+	default:
+		return Pos{origin.X - depth, origin.Y + col}
+	}
+}
+
+// local undoes transform, the row and column of pos seen from origin
+func (q quadrant) local(origin, pos Pos) (depth, col int) {
+	dx, dy := pos.X-origin.X, pos.Y-origin.Y
+	switch q {
+	case north:
+		return -dy, dx
+	case east:
+		return dx, dy
+	case south:
+		return dy, dx
+	default:
+		return -dx, dy
+	}
+}
+
+// slope is num/den, kept as a fraction so the edges of shadows come out exact. den is positive
+type slope struct {
+	num, den int
+}
+
+// the slope to the left edge of a tile
+func tileSlope(depth, col int) slope {
+	return slope{2*col - 1, 2 * depth}
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// the column depth*s lands in, rounding halves up for the start of a row and down for the end
+func roundTiesUp(depth int, s slope) int {
+	return floorDiv(2*depth*s.num+s.den, 2*s.den)
+}
+
+func roundTiesDown(depth int, s slope) int {
+	return -floorDiv(s.den-2*depth*s.num, 2*s.den)
+}
+
+type shadowcast struct {
+	level    *Level
+	origin   Pos
+	quadrant quadrant
+	maxDepth int
+	see      func(Pos)
+}
+
+// scans the row depth out from the origin between the start and end slopes, then the
+// rows beyond it that walls don't hide
+func (s *shadowcast) scan(depth int, start, end slope) {
+	if depth > s.maxDepth {
+		return
+	}
+	first, prevWall := true, false
+	for col := roundTiesUp(depth, start); col <= roundTiesDown(depth, end); col++ {
+		pos := s.quadrant.transform(s.origin, depth, col)
+		wall := !canSeeThrough(s.level, pos)
+		// the center of a floor tile has to be in the light for it to be seen
+		symmetric := col*start.den >= depth*start.num && col*end.den <= depth*end.num
+		if (wall || symmetric) && inRange(s.level, pos) {
+			s.see(pos)
+		}
+		if !first && prevWall && !wall {
+			start = tileSlope(depth, col)
+		}
+		if !first && !prevWall && wall {
+			s.scan(depth+1, start, tileSlope(depth, col))
+		}
+		first, prevWall = false, wall
+	}
+	if !first && !prevWall {
+		s.scan(depth+1, start, end)
+	}
+}
+
+// refreshSight forgets what the player could see and looks again from where they stand,
+// seeing only what's lit. Only the tiles that were visible are cleared, so it costs the
+// sight radius, not the map
+func (level *Level) refreshSight() {
+	for _, pos := range level.visible {
+		level.Map[pos.Y][pos.X].Visible = false
+	}
+	level.visible = level.visible[:0]
+	level.relight()
+	from := level.Player.Pos
+	level.fov().Compute(level, from, level.Player.Stats().SightRange, func(pos Pos) {
+		tile := &level.Map[pos.Y][pos.X]
+		if !tile.Visible && level.canMakeOut(from, pos) {
+			tile.Visible = true
+			tile.Seen = true
+			level.visible = append(level.visible, pos)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package game
+
+import "testing"
+
+// sights says which of to can be seen from from, by both Sees and Compute
+func sights(t *testing.T, level *Level, from Pos, to ...Pos) []bool {
+	t.Helper()
+	fov := ShadowCasting{}
+	seen := make(map[Pos]bool)
+	fov.Compute(level, from, 10, func(p Pos) { seen[p] = true })
+	sees := make([]bool, len(to))
+	for i, p := range to {
+		sees[i] = fov.Sees(level, from, p, 10)
+		if sees[i] != seen[p] {
+			t.Errorf("from %v: Sees %v says %v, Compute %v", from, p, sees[i], seen[p])
+		}
+	}
+	return sees
+}
+
+// A pillar hides what's straight behind it from both sides, but not what's beside it
+func TestPillarShadow(t *testing.T) {
+	level := drawnLevel(
+		"#########",
+		"#.......#",
+		"#.......#",
+		"#...#...#",
+		"#.......#",
+		"#.......#",
+		"#########",
+	)
+	left, pillar, right := Pos{2, 3}, Pos{4, 3}, Pos{6, 3}
+	got := sights(t, level, left, pillar, right, Pos{6, 1}, Pos{6, 5})
+	want := []bool{true, false, true, true}
+	for i, w := range want {
+		if got[i] != w {
+			t.Errorf("from the left: seeing tile %d is %v, want %v", i, got[i], w)
+		}
+	}
+	if sights(t, level, right, left)[0] {
+		t.Error("the right side sees round the pillar to the left")
+	}
+}
+
+// A closed door and deep grass are seen but not seen through, an open door is
+func TestDoorShadow(t *testing.T) {
+	tests := []struct {
+		name    string
+		between string
+		through bool
+	}{
+		{"closed door", "|", false},
+		{"open door", "/", true},
+		{"deep grass", `"`, false},
+	}
+	for _, tt := range tests {
+		level := drawnLevel(
+			"#########",
+			"#...#...#",
+			"#..."+tt.between+"...#",
+			"#...#...#",
+			"#########",
+		)
+		from, door, beyond := Pos{2, 2}, Pos{4, 2}, Pos{6, 2}
+		got := sights(t, level, from, door, beyond)
+		if !got[0] || got[1] != tt.through {
+			t.Errorf("%s: sees it %v and past it %v, want true and %v", tt.name, got[0], got[1], tt.through)
+		}
+		if sights(t, level, beyond, from)[0] != tt.through {
+			t.Errorf("%s: seeing back from beyond isn't %v", tt.name, tt.through)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package game_test
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/gorillana/rpg/dungeon"
+	"github.com/gorillana/rpg/game"
+)
+
+// an open level with one tile in pillars of every so many walled off at random
+func cave(size int, pillars int, seed int64) *game.Level {
+	rng := rand.New(rand.NewSource(seed))
+	level := game.NewLevel("cave", size, size, game.NewPlayer())
+	for y := range level.Map {
+		for x := range level.Map[y] {
+			level.Map[y][x].Rune = game.DirtFloor
+			if x == 0 || y == 0 || x == size-1 || y == size-1 || rng.Intn(pillars) == 0 {
+				level.Map[y][x].Rune = game.StoneWall
+			}
+		}
+	}
+	level.Player.Pos = game.Pos{X: size / 2, Y: size / 2}
+	level.Map[size/2][size/2].Rune = game.DirtFloor
+	return level
+}
+
+type fovLevel struct {
+	name  string
+	level *game.Level
+}
+
+var (
+	fovOnce sync.Once
+	fovMaps []fovLevel
+)
+
+// a 1000x1000 generated dungeon and a cave with a pillar in every 12 tiles, built once
+// for all the benchmarks
+func fovLevels() []fovLevel {
+	fovOnce.Do(func() {
+		fovMaps = []fovLevel{
+			{"dungeon", dungeon.Generate(1, 1000, 1000)},
+			{"cave", cave(1000, 12, 1)},
+		}
+	})
+	return fovMaps
+}
+
+var sightRadii = []int{8, 20, 50}
+
+// A look around from where the player starts
+func BenchmarkShadowCasting(b *testing.B) {
+	fov := game.ShadowCasting{}
+	for _, l := range fovLevels() {
+		for _, radius := range sightRadii {
+			b.Run(fmt.Sprintf("%s/radius=%d", l.name, radius), func(b *testing.B) {
+				seen := 0
+				for i := 0; i < b.N; i++ {
+					seen = 0
+					fov.Compute(l.level, l.level.Player.Pos, radius, func(game.Pos) { seen++ })
+				}
+				b.ReportMetric(float64(seen), "tiles")
+			})
+		}
+	}
+}
+
+// Every monster on the level checking whether it can see the player
+func BenchmarkSees(b *testing.B) {
+	fov := game.ShadowCasting{}
+	for _, l := range fovLevels() {
+		if len(l.level.Monsters) == 0 {
+			continue
+		}
+		for _, radius := range sightRadii {
+			b.Run(fmt.Sprintf("%s/radius=%d", l.name, radius), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					for pos := range l.level.Monsters {
+						fov.Sees(l.level, pos, l.level.Player.Pos, radius)
+					}
+				}
+			})
+		}
+	}
+}
+
+// Two floor tiles either both see each other or neither does
+func TestSeesSymmetric(t *testing.T) {
+	fov := game.ShadowCasting{}
+	const radius = 8
+	level := cave(24, 5, 2)
+	var floors []game.Pos
+	for y, row := range level.Map {
+		for x, tile := range row {
+			if tile.Rune == game.DirtFloor {
+				floors = append(floors, game.Pos{X: x, Y: y})
+			}
+		}
+	}
+	for i, a := range floors {
+		for _, b := range floors[i+1:] {
+			ab, ba := fov.Sees(level, a, b, radius), fov.Sees(level, b, a, radius)
+			if ab != ba {
+				t.Errorf("%v sees %v is %v, the other way round %v", a, b, ab, ba)
+			}
+		}
+	}
+}
+
+// Sees is true for exactly the tiles Compute calls see with, walls included
+func TestSeesMatchesCompute(t *testing.T) {
+	fov := game.ShadowCasting{}
+	level := cave(40, 6, 3)
+	for _, radius := range []int{1, 5, 12} {
+		for y := 1; y < 39; y += 3 {
+			for x := 1; x < 39; x += 3 {
+				from := game.Pos{X: x, Y: y}
+				seen := make(map[game.Pos]bool)
+				fov.Compute(level, from, radius, func(p game.Pos) { seen[p] = true })
+				for y2 := range level.Map {
+					for x2 := range level.Map[y2] {
+						to := game.Pos{X: x2, Y: y2}
+						if sees := fov.Sees(level, from, to, radius); sees != seen[to] {
+							t.Fatalf("radius %d from %v: Sees %v says %v, Compute %v", radius, from, to, sees, seen[to])
+						}
+					}
+				}
+			}
+		}
+	}
+}
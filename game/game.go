@@ -2,34 +2,96 @@ package game
 
 import (
 	"bufio"
+	"embed"
 	"encoding/csv"
+	"errors"
 	"fmt"
-	"math"
-	"os"
-	"path/filepath"
+	"io"
+	"io/fs"
+	"math/rand"
+	"path"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorillana/rpg/pathfinding"
 )
 
+// the maps that ship with the game are compiled into the binary so it runs from any directory
+//
+//go:embed maps
+var embeddedMaps embed.FS
+
+// DefaultMaps is the fs NewGame loads from when it isn't given one
+func DefaultMaps() fs.FS {
+	maps, err := fs.Sub(embeddedMaps, "maps")
+	if err != nil {
+		panic(err)
+	}
+	return maps
+}
+
 type Game struct {
-	LevelChans   []chan *Level
+	// the open windows, Run owns them once it starts and OpenWindow and CloseWindow
+	// inputs add and take them away
+	Windows      []*Window
 	InputChan    chan *Input
 	Levels       map[string]*Level
 	CurrentLevel *Level
+	GameOver     bool
+
+	// where the .map files and world.txt come from
+	maps fs.FS
+	// what NewItem makes items from, the maps' items.txt or the built in items
+	items ItemCatalog
+	// set instead of maps when the level was built in code
+	generate func() *Level
+	// see SetDiagonal, kept so restarted and loaded levels get it too
+	diagonal bool
+	// the action points the player's last input costs, wading is slow
+	turns int
 }
 
-func NewGame(numWindows int) *Game {
-	//if we're going to have some number of windows we're going to make one level channel for each window
-	levelChans := make([]chan *Level, numWindows)
-	for i := range levelChans {
-		levelChans[i] = make(chan *Level)
+// the windows there are at the start, all following the player until their View is changed
+func makeWindows(numWindows int) []*Window {
+	windows := make([]*Window, numWindows)
+	for i := range windows {
+		windows[i] = NewWindow(View{})
 	}
+	return windows
+}
+
+// NewGame loads every .map file and world.txt from the root of maps,
+// passing nil uses the maps embedded in the binary
+func NewGame(numWindows int, maps fs.FS) (*Game, error) {
+	windows := makeWindows(numWindows)
 	inputChan := make(chan *Input)
-	levels := loadLevels()
+	if maps == nil {
+		maps = DefaultMaps()
+	}
+	levels, items, err := loadLevels(maps)
+	if err != nil {
+		return nil, err
+	}
+
+	game := &Game{Windows: windows, InputChan: inputChan, Levels: levels, maps: maps, items: items}
+	err = game.loadWorldFile()
+	if err != nil {
+		return nil, err
+	}
+	game.CurrentLevel.refreshSight()
+	return game, nil
+}
 
-	game := &Game{levelChans, inputChan, levels, nil}
-	game.loadWorldFile()
-	game.CurrentLevel.lineOfSight()
+// NewGameFromLevel starts a game on a single level built in code, like a
+// generated dungeon. Restarting calls generate again for a fresh copy
+func NewGameFromLevel(numWindows int, generate func() *Level) *Game {
+	level := generate()
+	game := &Game{Windows: makeWindows(numWindows), InputChan: make(chan *Input), generate: generate, items: defaultItemCatalog}
+	game.Levels = map[string]*Level{level.Name: level}
+	game.CurrentLevel = level
+	game.CurrentLevel.refreshSight()
 	return game
 }
 
@@ -41,11 +103,25 @@ const (
 	Down
 	Left
 	Right
+	UpLeft
+	UpRight
+	DownLeft
+	DownRight
 	TakeAll
+	// closes the open door at Input.Pos, named so it doesn't clash with the CloseDoor tile
+	ShutDoor
 	TakeItem
 	DropItem
 	EquipItem
+	UnequipItem
+	UseItem
+	SaveGame
+	LoadGame
+	Restart
 	QuitGame
+	// OpenWindow starts drawing to Input.Window and CloseWindow stops, the rest of the game
+	// carries on as if nothing happened
+	OpenWindow
 	CloseWindow
 	MouseClick
 	Search // temp
@@ -53,9 +129,15 @@ const (
 
 // Tagged / Discriminatory Union / Sum Type
 type Input struct {
-	Typ          InputType
-	Item         *Item
-	LevelChannel chan *Level
+	Typ  InputType
+	Item *Item
+	// points at Item instead for inputs from remote windows, see ItemRef
+	Ref    *ItemRef
+	Window *Window
+	// the tile an input is aimed at, the door for ShutDoor
+	Pos Pos
+	// the hand EquipItem puts a ring on, 1 or 2, 0 lets the game pick
+	Ring int
 }
 
 // tile is alias for rune, lets us create an enum
@@ -64,6 +146,8 @@ type Tile struct {
 	OverlayRune rune
 	Visible     bool
 	Seen        bool
+	// what light sources add to the level's Ambient here, see LightAt
+	Light int
 }
 
 const (
@@ -95,41 +179,173 @@ type Entity struct {
 type Character struct {
 	Entity
 	Hitpoints    int
+	MaxHitpoints int
 	Strength     int
 	Speed        float64
 	ActionPoints float64
 	SightRange   int
+	Accuracy     int
+	Evasion      int
 	Items        []*Item
-	Helmet       *Item
-	Weapon       *Item
+	// can go into water
+	Swims bool
+	// how far its own glow reaches, 0 for none, see light.go
+	Light int
+
+	// equipment, see Stats for what it adds up to
+	Helmet *Item
+	Weapon *Item
+	Armor  *Item
+	Boots  *Item
+	Shield *Item
+	Amulet *Item
+	Ring1  *Item
+	Ring2  *Item
+}
+
+func (c *Character) slots() []**Item {
+	return []**Item{&c.Helmet, &c.Weapon, &c.Armor, &c.Boots, &c.Shield, &c.Amulet, &c.Ring1, &c.Ring2}
+}
+
+// Equipped is everything the character is wearing
+func (c *Character) Equipped() []*Item {
+	equipped := make([]*Item, 0, 8)
+	for _, slot := range c.slots() {
+		if *slot != nil {
+			equipped = append(equipped, *slot)
+		}
+	}
+	return equipped
+}
+
+// the slot holding an equipped item, nil if the character isn't wearing it
+func (c *Character) slotHolding(item *Item) **Item {
+	for _, slot := range c.slots() {
+		if *slot == item {
+			return slot
+		}
+	}
+	return nil
+}
+
+// where an item with the given slot goes. A ring goes on the hand asked for, with ring 0 it
+// fills the second hand only if the first is taken
+func (c *Character) slotFor(slot Slot, ring int) **Item {
+	switch slot {
+	case HeadSlot:
+		return &c.Helmet
+	case WeaponSlot:
+		return &c.Weapon
+	case BodySlot:
+		return &c.Armor
+	case FeetSlot:
+		return &c.Boots
+	case ShieldSlot:
+		return &c.Shield
+	case NeckSlot:
+		return &c.Amulet
+	case RingSlot:
+		if ring == 2 || ring == 0 && c.Ring1 != nil && c.Ring2 == nil {
+			return &c.Ring2
+		}
+		return &c.Ring1
+	}
+	return nil
+}
+
+// Stats adds the modifiers of everything equipped to the character's base values,
+// strength is the base attack. Gear can't push a stat below what the game needs to work
+func (c *Character) Stats() Stats {
+	stats := Stats{Attack: c.Strength, Speed: c.Speed, SightRange: c.SightRange, MaxHitpoints: c.MaxHitpoints, Accuracy: c.Accuracy, Evasion: c.Evasion, Light: c.Light}
+	for _, item := range c.Equipped() {
+		stats = stats.add(item.Modifiers)
+	}
+	if stats.Attack < 0 {
+		stats.Attack = 0
+	}
+	if stats.Defense < 0 {
+		stats.Defense = 0
+	}
+	if stats.Speed < 0.1 {
+		stats.Speed = 0.1
+	}
+	if stats.SightRange < 1 {
+		stats.SightRange = 1
+	}
+	if stats.MaxHitpoints < 1 {
+		stats.MaxHitpoints = 1
+	}
+	if stats.Accuracy < 0 {
+		stats.Accuracy = 0
+	}
+	if stats.Evasion < 0 {
+		stats.Evasion = 0
+	}
+	if stats.Light < 0 {
+		stats.Light = 0
+	}
+	return stats
+}
+
+// losing max hitpoints takes the current ones with it
+func (c *Character) clampHitpoints() {
+	max := c.Stats().MaxHitpoints
+	if c.Hitpoints > max {
+		c.Hitpoints = max
+	}
 }
 
 type Player struct {
 	Character
 }
 
-type GameEvent int
+type Level struct {
+	Name     string
+	Map      [][]Tile
+	Player   *Player
+	Monsters map[Pos]*Monster
+	Items    map[Pos][]*Item
+	Portals  map[Pos]*LevelPos
+	// the log shown to the player, a ring buffer written from TurnEvents
+	Events   []string
+	EventPos int
+	Debug    map[Pos]bool
+	// everything that happened since the last input, in order
+	TurnEvents []Event
+
+	// combat rolls, see Seed
+	rng *rand.Rand
+	// the way to the player for chasing monsters, nil until a monster needs it
+	flows map[mover]*flowField
+	// whether the player and monsters can step diagonally, see Game.SetDiagonal
+	Diagonal bool
+	// who still gets to act this tick, see scheduler.go
+	turnQueue *pathfinding.Queue[*Monster]
+	// how the player and monsters see, ShadowCasting when nil
+	FOV FOV
+	// the tiles marked Visible, so the next look only has to clear those
+	visible []Pos
+	// light everywhere on the level before any sources, from 0 to MaxLight
+	Ambient int
+	// the tiles light sources reach and where the wall torches are, see relight
+	lit     []Pos
+	torches []Pos
+}
 
-const (
-	Move GameEvent = iota
-	DoorOpen
-	Attack
-	Hit
-	Portal
-	Pickup
-	Drop
-)
+// Seed restarts the level's combat rolls so the same fight plays out the same way
+func (level *Level) Seed(seed int64) {
+	level.rng = rand.New(rand.NewSource(seed))
+}
 
-type Level struct {
-	Map       [][]Tile
-	Player    *Player
-	Monsters  map[Pos]*Monster
-	Items     map[Pos][]*Item
-	Portals   map[Pos]*LevelPos
-	Events    []string
-	EventPos  int
-	Debug     map[Pos]bool
-	LastEvent GameEvent
+// AttackResult is how one swing went. Pos is where the defender stands
+type AttackResult struct {
+	Attacker string
+	Defender string
+	Pos      Pos
+	Hit      bool
+	Crit     bool
+	Damage   int
+	Killed   bool
 }
 
 func (level *Level) DropItem(itemToDrop *Item, character *Character) {
@@ -140,15 +356,38 @@ func (level *Level) DropItem(itemToDrop *Item, character *Character) {
 		if item == itemToDrop {
 			character.Items = append(character.Items[:i], character.Items[i+1:]...)
 			level.Items[pos] = append(level.Items[pos], item)
-			level.AddEvent(character.Name + " dropped: " + item.Name)
+			level.emit(Event{Kind: Drop, Actor: character.Name, Target: item.Name, Pos: pos})
 			return
 		}
 	}
 
 }
 
+// UseItem drinks a consumable from the character's bag
+func (level *Level) UseItem(itemToUse *Item, character *Character) {
+	if itemToUse == nil || itemToUse.Typ != Consumable {
+		return
+	}
+	for i, item := range character.Items {
+		if item == itemToUse {
+			character.Items = append(character.Items[:i], character.Items[i+1:]...)
+			amount := int(item.power)
+			switch item.Effect {
+			case Heal:
+				before := character.Hitpoints
+				character.Hitpoints += amount
+				character.clampHitpoints()
+				level.emit(Event{Kind: Healed, Actor: character.Name, Target: item.Name, Pos: character.Pos, Amount: character.Hitpoints - before})
+			case GainStrength:
+				character.Strength += amount
+				level.emit(Event{Kind: Strengthened, Actor: character.Name, Target: item.Name, Pos: character.Pos, Amount: amount})
+			}
+			return
+		}
+	}
+}
+
 func (level *Level) MoveItem(itemToMove *Item, character *Character) {
-	fmt.Println("Move Item!")
 	pos := character.Pos
 	items := level.Items[pos]
 
@@ -157,34 +396,65 @@ func (level *Level) MoveItem(itemToMove *Item, character *Character) {
 			items = append(items[:i], items[i+1:]...)
 			level.Items[pos] = items
 			character.Items = append(character.Items, item)
-			level.AddEvent(character.Name + " picked up: " + item.Name)
+			level.emit(Event{Kind: Pickup, Actor: character.Name, Target: item.Name, Pos: pos})
 			return
 		}
 	}
-	panic("Tried to move an item we were not on top of")
 }
 
-// Combine the two attack functions into 1 somehow
-func (level *Level) Attack(c1, c2 *Character) {
-	c1.ActionPoints--
-	// base strength
-	c1AttackPower := c1.Strength
-	// with weapon
-	if c1.Weapon != nil {
-		c1AttackPower = int(float64(c1AttackPower) * c1.Weapon.power)
+// how much defense it takes to halve damage, 20 cuts it to a third and so on
+const defenseScale = 10
+
+const (
+	// hit chance is accuracy minus evasion but there's always some chance either way
+	minHitChance = 5
+	maxHitChance = 95
+	// percent of hits that do double damage
+	critChance = 5
+	// damage rolls land within this percent either side of the attack
+	damageSpread = 25
+)
+
+// Attack has c1 swing at c2, who stands at pos. Whoever called it charges c1 for the turn
+func (level *Level) Attack(c1, c2 *Character, pos Pos) AttackResult {
+	attacker, defender := c1.Stats(), c2.Stats()
+	result := AttackResult{Attacker: c1.Name, Defender: c2.Name, Pos: pos}
+
+	hitChance := attacker.Accuracy - defender.Evasion
+	if hitChance < minHitChance {
+		hitChance = minHitChance
+	} else if hitChance > maxHitChance {
+		hitChance = maxHitChance
+	}
+	result.Hit = level.rng.Intn(100) < hitChance
+	if !result.Hit {
+		level.emit(Event{Kind: Miss, Actor: c1.Name, Target: c2.Name, Pos: pos})
+		return result
 	}
-	damage := c1AttackPower
 
-	if c2.Helmet != nil {
-		damage = int(float64(damage) * (1.0 - c2.Helmet.power))
+	damage := attacker.Attack * (100 - damageSpread + level.rng.Intn(2*damageSpread+1)) / 100
+	result.Crit = level.rng.Intn(100) < critChance
+	if result.Crit {
+		damage *= 2
+	}
+	damage = damage * defenseScale / (defenseScale + defender.Defense)
+	// a hit always hurts a little
+	if damage < 1 && attacker.Attack > 0 {
+		damage = 1
 	}
 	c2.Hitpoints -= damage
+	result.Damage = damage
+	result.Killed = c2.Hitpoints <= 0
 
-	if c2.Hitpoints > 0 {
-		level.AddEvent(c1.Name + "Attacked" + c2.Name + " for " + strconv.Itoa(damage))
-	} else {
-		level.AddEvent(c1.Name + " Killed " + c2.Name)
+	kind := Hit
+	if result.Crit {
+		kind = Crit
 	}
+	level.emit(Event{Kind: kind, Actor: c1.Name, Target: c2.Name, Pos: pos, Amount: damage})
+	if result.Killed {
+		level.emit(Event{Kind: Kill, Actor: c1.Name, Target: c2.Name, Pos: pos})
+	}
+	return result
 }
 
 func (level *Level) AddEvent(event string) {
@@ -198,274 +468,276 @@ func (level *Level) AddEvent(event string) {
 	}
 }
 
-func (level *Level) lineOfSight() {
-	pos := level.Player.Pos
-	dist := level.Player.SightRange
-
-	for y := pos.Y - dist; y <= pos.Y+dist; y++ {
-		for x := pos.X - dist; x <= pos.X+dist; x++ {
-			xDelta := pos.X - x
-			yDelta := pos.Y - y
-			d := math.Sqrt(float64(xDelta*xDelta + yDelta*yDelta))
-			if d <= float64(dist) {
-				level.bresenham(pos, Pos{x, y})
-			}
-		}
-	}
-}
-
-// Reversing the order of the results when necessary
-func (level *Level) bresenham(start Pos, end Pos) {
-	// make([]slice) - allocating memory
-	steep := math.Abs(float64(end.Y-start.Y)) > math.Abs(float64(end.X-start.X))
-
-	if steep {
-		start.X, start.Y = start.Y, start.X
-		end.X, end.Y = end.Y, end.X
-	}
-	deltaY := int(math.Abs(float64(end.Y - start.Y)))
-	err := 0
-
-	y := start.Y
-	ystep := 1
-	if start.Y >= end.Y {
-		ystep = -1
-	}
-
-	if start.X > end.X {
-
-		deltaX := start.X - end.X
-
-		for x := start.X; x > end.X; x-- {
-			var pos Pos
-			if steep {
-				pos = Pos{y, x}
-			} else {
-				pos = Pos{x, y}
-			}
-			level.Map[pos.Y][pos.X].Visible = true
-			level.Map[pos.Y][pos.X].Seen = true
-			if !canSeeThrough(level, pos) {
-				return
-			}
-			err += deltaY
-			if 2*err >= deltaX {
-				y += ystep
-				err -= deltaX
-			}
-		}
-	} else {
-
-		deltaX := end.X - start.X
-		for x := start.X; x < end.X; x++ {
-			var pos Pos
-			if steep {
-				pos = Pos{y, x}
-			} else {
-				pos = Pos{x, y}
-			}
-			level.Map[pos.Y][pos.X].Visible = true
-			level.Map[pos.Y][pos.X].Seen = true
-			if !canSeeThrough(level, pos) {
-				return
-			}
-			err += deltaY
-			if 2*err >= deltaX {
-				y += ystep
-				err -= deltaX
-			}
-		}
-	}
-}
-
-func (game *Game) loadWorldFile() {
-	file, err := os.Open("game/maps/world.txt")
+func (game *Game) loadWorldFile() error {
+	file, err := game.maps.Open("world.txt")
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer file.Close()
 	csvReader := csv.NewReader(file)
 	csvReader.FieldsPerRecord = -1
 	csvReader.TrimLeadingSpace = true
-	rows, err := csvReader.ReadAll()
-	if err != nil {
-		panic(err)
-	}
 
-	for rowIndex, row := range rows {
+	for rowIndex := 0; ; rowIndex++ {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("world.txt: %w", err)
+		}
+		line, _ := csvReader.FieldPos(0)
+
 		// set current level
 		if rowIndex == 0 {
 			game.CurrentLevel = game.Levels[row[0]]
 			if game.CurrentLevel == nil {
-				fmt.Println("couldn't find currentlevel name in world file")
-				panic(nil)
+				return &ErrUnknownLevel{row[0], line}
+			}
+			continue
+		}
+		// level,light,n sets how light a level is without torches
+		if len(row) == 3 && row[1] == "light" {
+			level := game.Levels[row[0]]
+			if level == nil {
+				return &ErrUnknownLevel{row[0], line}
+			}
+			level.Ambient, err = strconv.Atoi(row[2])
+			if err != nil || level.Ambient < 0 || level.Ambient > MaxLight {
+				return fmt.Errorf("world.txt:%d: light should be 0 to %d, got %q", line, MaxLight, row[2])
 			}
 			continue
 		}
+		if len(row) != 6 {
+			return fmt.Errorf("world.txt:%d: expected level,x,y,level,x,y or level,light,n but got %d fields", line, len(row))
+		}
 		levelWithPortal := game.Levels[row[0]]
 		if levelWithPortal == nil {
-			fmt.Println("couldn't find level name 1 in world file")
-			panic(nil)
+			return &ErrUnknownLevel{row[0], line}
 		}
 
 		x, err := strconv.ParseInt(row[1], 10, 64)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("world.txt:%d: bad x: %w", line, err)
 		}
 
 		y, err := strconv.ParseInt(row[2], 10, 64)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("world.txt:%d: bad y: %w", line, err)
 		}
 		pos := Pos{int(x), int(y)}
 
 		levelToTeleportTo := game.Levels[row[3]]
-		if levelWithPortal == nil {
-			fmt.Println("couldn't find level name 2 in world file")
-			panic(nil)
+		if levelToTeleportTo == nil {
+			return &ErrUnknownLevel{row[3], line}
 		}
 
 		x, err = strconv.ParseInt(row[4], 10, 64)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("world.txt:%d: bad destination x: %w", line, err)
 		}
 
 		y, err = strconv.ParseInt(row[5], 10, 64)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("world.txt:%d: bad destination y: %w", line, err)
 		}
 		posToTeleportTo := Pos{int(x), int(y)}
 		levelWithPortal.Portals[pos] = &LevelPos{levelToTeleportTo, posToTeleportTo}
 	}
 
+	if game.CurrentLevel == nil {
+		return fmt.Errorf("world.txt: empty, the first line should name the starting level")
+	}
+	return nil
 }
 
-// Todo take in a path
-func loadLevels() map[string]*Level {
+// NewLevel returns an empty level, every tile is Blank
+func NewLevel(name string, width, height int, player *Player) *Level {
+	// level set to a blank/zeroed out level
+	level := &Level{}
+	level.Name = name
+	level.Debug = make(map[Pos]bool)
+	level.Events = make([]string, 10)
+	level.Player = player
+	level.Map = make([][]Tile, height)
+	// init monsters
+	level.Monsters = make(map[Pos]*Monster)
+	level.Items = make(map[Pos][]*Item)
+	level.Portals = make(map[Pos]*LevelPos)
+	level.Ambient = MaxLight
+	level.Seed(time.Now().UnixNano())
+
+	// go through each row and make an array for the row
+	for i := range level.Map {
+		level.Map[i] = make([]Tile, width)
+	}
+	return level
+}
 
+func NewPlayer() *Player {
 	player := &Player{}
-	// TODO where should we initialize the player?
 	player.Strength = 5
 	player.Hitpoints = 20
+	player.MaxHitpoints = 20
 	player.Name = "GOrillana"
 	player.Rune = '@'
 	player.Speed = 1.0
 	player.ActionPoints = 0.0
 	player.SightRange = 7
+	player.Accuracy = 85
+	player.Evasion = 10
+	player.Swims = true
+	return player
+}
+
+func loadLevels(maps fs.FS) (map[string]*Level, ItemCatalog, error) {
+	// every level shares the one player
+	player := NewPlayer()
+
+	monsters, err := LoadMonsterDefs(maps)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, err := LoadItemCatalog(maps)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = monsters.check(items)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	levels := make(map[string]*Level)
 
-	filenames, err := filepath.Glob("game/maps/*.map")
+	filenames, err := fs.Glob(maps, "*.map")
 	if err != nil {
-		panic("file error")
+		return nil, nil, err
+	}
+	if len(filenames) == 0 {
+		return nil, nil, fmt.Errorf("no .map files found")
 	}
 
 	for _, filename := range filenames {
-		fmt.Println("loading:", filename)
-		extIndex := strings.LastIndex(filename, ".map")
-		lastSlashIndex := strings.LastIndex(filename, "\\")
-		levelName := filename[lastSlashIndex+1 : extIndex]
-		fmt.Println("name: ", levelName)
-		file, err := os.Open(filename)
+		level, err := loadLevel(maps, filename, player, monsters, items)
 		if err != nil {
-			panic(err)
+			return nil, nil, err
 		}
-		defer file.Close()
+		levels[level.Name] = level
+	}
+	return levels, items, nil
+}
 
-		scanner := bufio.NewScanner(file)
-		levelLines := make([]string, 0)
-		longestRow := 0
-		index := 0
-		for scanner.Scan() {
+func loadLevel(maps fs.FS, filename string, player *Player, monsters MonsterDefs, items ItemCatalog) (*Level, error) {
+	// fs paths always use forward slashes whatever the OS
+	levelName := strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+	file, err := maps.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	levelLines := make([]string, 0)
+	longestRow := 0
+	index := 0
+	for scanner.Scan() {
+
+		// array will have string for each row
+		levelLines = append(levelLines, scanner.Text())
+		// glyphs can be any character, so the map is as wide as its longest row in runes
+		if n := utf8.RuneCountInString(levelLines[index]); n > longestRow {
+			longestRow = n
+		}
+		index++
+	}
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if longestRow == 0 {
+		return nil, fmt.Errorf("%s: map is empty", filename)
+	}
 
-			// array will have string for each row
-			levelLines = append(levelLines, scanner.Text())
-			if len(levelLines[index]) > longestRow {
-				longestRow = len(levelLines[index])
-			}
-			index++
-		}
-
-		// level set to a blank/zeroed out level
-		level := &Level{}
-		level.Debug = make(map[Pos]bool)
-		level.Events = make([]string, 10)
-		level.Player = player
-		level.Map = make([][]Tile, len(levelLines))
-		// init monsters
-		level.Monsters = make(map[Pos]*Monster)
-		level.Items = make(map[Pos][]*Item)
-		level.Portals = make(map[Pos]*LevelPos)
-
-		// go through each row and make an array for the row
-		for i := range level.Map {
-			level.Map[i] = make([]Tile, longestRow)
-		}
-
-		// render tiles
-		for y := 0; y < len(level.Map); y++ {
-			line := levelLines[y]
-			for x, c := range line {
-				pos := Pos{x, y}
-				var t Tile
-				t.OverlayRune = Blank
-				switch c {
-				case ' ', '\t', '\n', '\r':
-					t.Rune = Blank
-				case '#':
-					t.Rune = StoneWall
-				case '|':
-					t.OverlayRune = CloseDoor
-					t.Rune = Pending
-				case '/':
-					t.OverlayRune = OpenDoor
-					t.Rune = Pending
-				case 'u':
-					t.OverlayRune = UpStair
-					t.Rune = Pending
-				case 'd':
-					t.OverlayRune = DownStair
-					t.Rune = Pending
-				case 's':
-					level.Items[pos] = append(level.Items[pos], NewSword(pos))
+	level := NewLevel(levelName, longestRow, len(levelLines), player)
+
+	// render tiles
+	var glyphErrs []error
+	for y := 0; y < len(level.Map); y++ {
+		line := []rune(levelLines[y])
+		for x, c := range line {
+			pos := Pos{x, y}
+			var t Tile
+			t.OverlayRune = Blank
+			switch c {
+			case ' ', '\t', '\n', '\r':
+				t.Rune = Blank
+			case '#':
+				t.Rune = StoneWall
+			case WallTorch:
+				t.Rune = StoneWall
+				t.OverlayRune = WallTorch
+			case '|':
+				t.OverlayRune = CloseDoor
+				t.Rune = Pending
+			case '/':
+				t.OverlayRune = OpenDoor
+				t.Rune = Pending
+			case 'u':
+				t.OverlayRune = UpStair
+				t.Rune = Pending
+			case 'd':
+				t.OverlayRune = DownStair
+				t.Rune = Pending
+			case '.':
+				t.Rune = DirtFloor
+			case '~':
+				t.Rune = Water
+			case '=':
+				t.Rune = Lava
+			case '"':
+				t.Rune = DeepGrass
+			case '_':
+				t.Rune = Ice
+			case '@':
+				level.Player.X = x
+				level.Player.Y = y
+				t.Rune = Pending
+			default:
+				def, exists := monsters[c]
+				if exists {
+					level.Monsters[pos] = def.spawn(pos, items)
 					t.Rune = Pending
-				case 'h':
-					level.Items[pos] = append(level.Items[pos], NewHelmet(pos))
-					t.Rune = Pending
-				case '.':
-					t.Rune = DirtFloor
-				case '@':
-					level.Player.X = x
-					level.Player.Y = y
-					t.Rune = Pending
-				case 'B':
-					level.Monsters[pos] = NewBat(pos)
-					t.Rune = Pending
-				case 'D':
-					level.Monsters[pos] = NewDragon(pos)
-					t.Rune = Pending
-				case 'S':
-					level.Monsters[pos] = NewSpider(pos)
+					break
+				}
+				itemDef := items.ByGlyph(c)
+				if itemDef != nil {
+					level.Items[pos] = append(level.Items[pos], itemDef.spawn(pos))
 					t.Rune = Pending
-				default:
-					panic("Invalid character in map")
+					break
 				}
-				level.Map[y][x] = t
+				// keep going so every bad character in the file gets reported at once
+				glyphErrs = append(glyphErrs, &ErrUnknownGlyph{filename, y + 1, x + 1, c})
+				t.Rune = Blank
 			}
+			level.Map[y][x] = t
 		}
+	}
 
-		// we should use bfs to find first floor tile
-		// go over map again (draw order)
-		for y, row := range level.Map {
-			for x, tile := range row {
-				// fill in the player/pending square with similar tiles
-				if tile.Rune == Pending {
-					level.Map[y][x].Rune = level.bfsFloor(Pos{x, y})
-				}
+	if len(glyphErrs) > 0 {
+		return nil, errors.Join(glyphErrs...)
+	}
+
+	// we should use bfs to find first floor tile
+	// go over map again (draw order)
+	for y, row := range level.Map {
+		for x, tile := range row {
+			// fill in the player/pending square with similar tiles
+			if tile.Rune == Pending {
+				level.Map[y][x].Rune = level.bfsFloor(Pos{x, y})
 			}
 		}
-		levels[levelName] = level
 	}
-	return levels
+	return level, nil
 }
 
 func inRange(level *Level, pos Pos) bool {
@@ -476,6 +748,18 @@ func inRange(level *Level, pos Pos) bool {
 // added function to simplify handleInput
 // checks to see if a tile can be walked on
 func canWalk(level *Level, pos Pos) bool {
+	if canPass(level, pos) {
+		_, exists := level.Monsters[pos]
+		if exists {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// canPass is canWalk without the monsters, only walls and closed doors are in the way
+func canPass(level *Level, pos Pos) bool {
 	if inRange(level, pos) {
 		t := level.Map[pos.Y][pos.X]
 		switch t.Rune {
@@ -486,10 +770,6 @@ func canWalk(level *Level, pos Pos) bool {
 		case CloseDoor:
 			return false
 		}
-		_, exists := level.Monsters[pos]
-		if exists {
-			return false
-		}
 		return true
 	}
 	return false
@@ -499,7 +779,7 @@ func canSeeThrough(level *Level, pos Pos) bool {
 	if inRange(level, pos) {
 		t := level.Map[pos.Y][pos.X]
 		switch t.Rune {
-		case StoneWall, Blank:
+		case StoneWall, Blank, DeepGrass:
 			return false
 		}
 		switch t.OverlayRune {
@@ -513,233 +793,273 @@ func canSeeThrough(level *Level, pos Pos) bool {
 }
 
 func checkDoor(level *Level, pos Pos) {
-	t := level.Map[pos.Y][pos.X]
-	if t.OverlayRune == CloseDoor {
-		level.Map[pos.Y][pos.X].OverlayRune = OpenDoor
-		level.LastEvent = DoorOpen
-		level.lineOfSight()
+	level.openDoor(level.Player.Name, pos)
+}
+
+// openDoor opens the door at pos if it's shut, reporting whether it was
+func (level *Level) openDoor(actor string, pos Pos) bool {
+	if level.Map[pos.Y][pos.X].OverlayRune != CloseDoor {
+		return false
+	}
+	level.Map[pos.Y][pos.X].OverlayRune = OpenDoor
+	level.emit(Event{Kind: DoorOpen, Actor: actor, Pos: pos})
+	level.invalidateFlow()
+	level.refreshSight()
+	return true
+}
+
+// OpenDoorsNear lists the open doors touching pos, diagonals included
+func (level *Level) OpenDoorsNear(pos Pos) []Pos {
+	doors := make([]Pos, 0)
+	for y := pos.Y - 1; y <= pos.Y+1; y++ {
+		for x := pos.X - 1; x <= pos.X+1; x++ {
+			p := Pos{x, y}
+			if p != pos && inRange(level, p) && level.Map[y][x].OverlayRune == OpenDoor {
+				doors = append(doors, p)
+			}
+		}
 	}
+	return doors
+}
+
+// closes the open door at pos next to the player, nothing can be standing or lying in the doorway
+func (level *Level) closeDoor(pos Pos) {
+	p := level.Player.Pos
+	dx, dy := pos.X-p.X, pos.Y-p.Y
+	if pos == p || dx < -1 || dx > 1 || dy < -1 || dy > 1 || !inRange(level, pos) {
+		level.message("That's too far away to close")
+		return
+	}
+	if level.Map[pos.Y][pos.X].OverlayRune != OpenDoor {
+		level.message("There's no open door there")
+		return
+	}
+	if _, exists := level.Monsters[pos]; exists {
+		level.message("Something is standing in the doorway")
+		return
+	}
+	if len(level.Items[pos]) > 0 {
+		level.message("Something on the floor is blocking the door")
+		return
+	}
+	level.Map[pos.Y][pos.X].OverlayRune = CloseDoor
+	level.emit(Event{Kind: DoorClose, Actor: level.Player.Name, Pos: pos})
+	level.invalidateFlow()
+	level.refreshSight()
 }
 
 func (game *Game) Move(to Pos) {
 	level := game.CurrentLevel
 	player := level.Player
+	to = level.slide(player.Pos, to, player.walkable)
 
 	levelAndPos := level.Portals[to]
 	if levelAndPos != nil {
+		// windows still watching the old level shouldn't hear this turn again every turn
+		level.TurnEvents = nil
 		game.CurrentLevel = levelAndPos.Level
 		game.CurrentLevel.Player.Pos = levelAndPos.Pos
+		// the ui only sees the new level, anything left from the last visit is stale
+		game.CurrentLevel.TurnEvents = nil
+		// whoever was waiting for a turn when the player left has been waiting since
+		game.CurrentLevel.turnQueue = nil
+		game.CurrentLevel.refreshSight()
+		game.CurrentLevel.emit(Event{Kind: Portal, Actor: player.Name, Target: game.CurrentLevel.Name, Pos: levelAndPos.Pos})
 	} else {
 		player.Pos = to
-		level.LastEvent = Move
-		for y, row := range level.Map {
-			for x := range row {
-				level.Map[y][x].Visible = false
-			}
-		}
-
-		level.lineOfSight()
-		fmt.Println("Player:", player.Pos)
+		level.emit(Event{Kind: Move, Actor: player.Name, Pos: to})
+		level.refreshSight()
+		game.turns = moveTurns(level, to)
+		level.burn(&player.Character, to)
 	}
 }
 
-
 func (game *Game) resolveMovement(pos Pos) {
 	level := game.CurrentLevel
 	monster, exists := level.Monsters[pos]
 	if exists {
-		level.Attack(&level.Player.Character, &monster.Character)
-		level.LastEvent = Attack
-		// monster dies
-		if monster.Hitpoints <= 0 {
+		if level.Attack(&level.Player.Character, &monster.Character, pos).Killed {
 			monster.Kill(level)
 		}
-		// player dies
-		if level.Player.Hitpoints <= 0 {
-			//ui.DrawDeathRect("You have died")
-			panic("ded")
-		}
-	} else if canWalk(level, pos) {
+	} else if level.Player.walkable(level, pos) {
 		game.Move(pos)
 	} else {
 		checkDoor(level, pos)
 	}
 }
 
-func equip(c *Character, itemtoEquip *Item) {
+// EquipItem moves an item from the bag into its slot, whatever was there goes back in the bag.
+// ring is the hand a ring goes on, see slotFor
+func (level *Level) EquipItem(itemToEquip *Item, c *Character, ring int) {
+	// treasure and the like stay in the bag
+	if itemToEquip == nil || itemToEquip.Slot == NoSlot {
+		return
+	}
 	for i, item := range c.Items {
-		if item == itemtoEquip {
+		if item == itemToEquip {
 			c.Items = append(c.Items[:i], c.Items[i+1:]...)
-			if itemtoEquip.Typ == Helmet {
-				c.Helmet = itemtoEquip
-			} else if itemtoEquip.Typ == Weapon {
-				c.Weapon = itemtoEquip
+			slot := c.slotFor(itemToEquip.Slot, ring)
+			if *slot != nil {
+				c.Items = append(c.Items, *slot)
+				level.emit(Event{Kind: Unequip, Actor: c.Name, Target: (*slot).Name, Pos: c.Pos})
 			}
+			*slot = itemToEquip
+			level.emit(Event{Kind: Equip, Actor: c.Name, Target: itemToEquip.Name, Pos: c.Pos})
+			c.clampHitpoints()
 			return
 		}
 	}
-	panic("someone tried to equip a thing they don't have")
+}
+
+// UnequipItem takes an item off and puts it back in the bag
+func (level *Level) UnequipItem(itemToRemove *Item, c *Character) {
+	if itemToRemove == nil {
+		return
+	}
+	slot := c.slotHolding(itemToRemove)
+	if slot == nil {
+		return
+	}
+	*slot = nil
+	c.Items = append(c.Items, itemToRemove)
+	c.clampHitpoints()
+	level.emit(Event{Kind: Unequip, Actor: c.Name, Target: itemToRemove.Name, Pos: c.Pos})
+}
+
+// marks the game as lost, the ui shows its death screen when it sees the Death event
+func (game *Game) playerDied() {
+	game.GameOver = true
+	game.CurrentLevel.emit(Event{Kind: Death, Actor: game.CurrentLevel.Player.Name, Pos: game.CurrentLevel.Player.Pos})
+}
+
+// throws away every level and starts again from the map files
+func (game *Game) restart() error {
+	if game.generate != nil {
+		level := game.generate()
+		game.Levels = map[string]*Level{level.Name: level}
+		game.CurrentLevel = level
+		game.SetDiagonal(game.diagonal)
+		game.CurrentLevel.refreshSight()
+		game.GameOver = false
+		return nil
+	}
+
+	levels, items, err := loadLevels(game.maps)
+	if err != nil {
+		return err
+	}
+	restarted := &Game{Levels: levels, maps: game.maps, items: items}
+	err = restarted.loadWorldFile()
+	if err != nil {
+		return err
+	}
+	game.Levels = restarted.Levels
+	game.CurrentLevel = restarted.CurrentLevel
+	game.items = restarted.items
+	game.SetDiagonal(game.diagonal)
+	game.CurrentLevel.refreshSight()
+	game.GameOver = false
+	return nil
 }
 
 // allows user to use d-pad to move character
 func (game *Game) handleInput(input *Input) {
 	level := game.CurrentLevel
 	p := level.Player
+
+	// once the player is dead only restarting, loading or closing does anything
+	if game.GameOver {
+		switch input.Typ {
+		case Restart, LoadGame:
+		default:
+			return
+		}
+	}
+
+	if !level.resolveItem(input) {
+		// nothing happens and no turn passes
+		input.Typ = None
+		return
+	}
+
+	// a new turn, the ui has already seen the last one's events
+	level.TurnEvents = nil
+	game.turns = 1
+
 	switch input.Typ {
-	case Up:
-		newPos := Pos{p.X, p.Y - 1}
-		game.resolveMovement(newPos)
-	case Down:
-		newPos := Pos{p.X, p.Y + 1}
-		game.resolveMovement(newPos)
-	case Left:
-		newPos := Pos{p.X - 1, p.Y}
-		game.resolveMovement(newPos)
-	case Right:
-		newPos := Pos{p.X + 1, p.Y}
-		game.resolveMovement(newPos)
+	case Up, Down, Left, Right, UpLeft, UpRight, DownLeft, DownRight:
+		dir := moveDirections[input.Typ]
+		newPos := Pos{p.X + dir.X, p.Y + dir.Y}
+		// diagonals only when they're turned on, and never around a corner
+		if level.canStep(p.Pos, newPos) {
+			game.resolveMovement(newPos)
+		}
+	case ShutDoor:
+		level.closeDoor(input.Pos)
 	case TakeAll:
-		for _, item := range level.Items[p.Pos] {
+		// MoveItem shrinks the slice under us, so walk a copy
+		items := append([]*Item(nil), level.Items[p.Pos]...)
+		for _, item := range items {
 			level.MoveItem(item, &level.Player.Character)
 		}
-		level.LastEvent = Pickup
 	case TakeItem:
 		level.MoveItem(input.Item, &level.Player.Character)
-		level.LastEvent = Pickup
 	case EquipItem:
-		equip(&level.Player.Character, input.Item)
+		level.EquipItem(input.Item, &level.Player.Character, input.Ring)
+		level.refreshSight()
+	case UnequipItem:
+		level.UnequipItem(input.Item, &level.Player.Character)
+		level.refreshSight()
+	case UseItem:
+		level.UseItem(input.Item, &level.Player.Character)
 	case DropItem:
 		level.DropItem(input.Item, &level.Player.Character)
-		level.LastEvent = Drop
-
-	case CloseWindow:
-		close(input.LevelChannel)
-		chanIndex := 0
-		for i, c := range game.LevelChans {
-			if c == input.LevelChannel {
-				chanIndex = i
-				break
-			}
+	case SaveGame:
+		game.saveToFile()
+	case LoadGame:
+		game.loadFromFile()
+		game.GameOver = game.CurrentLevel.Player.Hitpoints <= 0
+	case Restart:
+		err := game.restart()
+		if err != nil {
+			level.message("Couldn't restart: " + err.Error())
 		}
-		game.LevelChans = append(game.LevelChans[:chanIndex], game.LevelChans[chanIndex+1:]...)
 	}
 }
 
-func getNeighbors(level *Level, pos Pos) []Pos {
-
-	neighbors := make([]Pos, 0, 4)
-
-	// look at player adjacent tiles
-	left := Pos{pos.X - 1, pos.Y}
-	right := Pos{pos.X + 1, pos.Y}
-	up := Pos{pos.X, pos.Y - 1}
-	down := Pos{pos.X, pos.Y + 1}
-
-	if canWalk(level, right) {
-		neighbors = append(neighbors, right)
-	}
-	if canWalk(level, left) {
-		neighbors = append(neighbors, left)
-	}
-	if canWalk(level, up) {
-		neighbors = append(neighbors, up)
-	}
-	if canWalk(level, down) {
-		neighbors = append(neighbors, down)
+// the adjacent tiles ok lets through that can be stepped to from pos
+func neighborsWhere(level *Level, pos Pos, ok func(*Level, Pos) bool) []Pos {
+	dirs := level.directions()
+	neighbors := make([]Pos, 0, len(dirs))
+	for _, dir := range dirs {
+		next := Pos{pos.X + dir.X, pos.Y + dir.Y}
+		if ok(level, next) && level.canStep(pos, next) {
+			neighbors = append(neighbors, next)
+		}
 	}
-
 	return neighbors
 }
 
-// breadth-first search
+// bfsFloor is what's under something standing at start, the floor nearest to it
 func (level *Level) bfsFloor(start Pos) rune {
-	//0 - how big to make slice at start
-	//8 - optional, how much space to reserve; reserves 8 spaces
-	frontier := make([]Pos, 0, 8)
-	frontier = append(frontier, start)
-	visited := make(map[Pos]bool)
-	visited[start] = true
-
-	for len(frontier) > 0 {
-		current := frontier[0]
-		currentTile := level.Map[current.Y][current.X]
-		switch currentTile.Rune {
-		// finds closest foor tile to something's that got a char/monst on top of it
-		case DirtFloor:
-			return DirtFloor
-		default:
-		}
-
-		// start at second element to the end
-		frontier = frontier[1:]
-		for _, next := range getNeighbors(level, current) {
-			if !visited[next] {
-				frontier = append(frontier, next)
-				visited[next] = true
-			}
-		}
+	pos, found := pathfinding.BFS[Pos](levelGraph{level, canWalk}, []Pos{start}, func(pos Pos) bool {
+		return level.Map[pos.Y][pos.X].Rune == DirtFloor
+	})
+	if !found {
+		return DirtFloor
 	}
-	return DirtFloor
+	return level.Map[pos.Y][pos.X].Rune
 }
 
-func (level *Level) aStar(start Pos, goal Pos) []Pos {
-	// priority queue
-	frontier := make(pqueue, 0, 8)
-	frontier = frontier.push(start, 1)
-	cameFrom := make(map[Pos]Pos)
-	cameFrom[start] = start
-	costSoFar := make(map[Pos]int)
-	costSoFar[start] = 0
-
-	var current Pos
-	for len(frontier) > 0 {
-
-		frontier, current = frontier.pop()
-
-		if current == goal {
-			path := make([]Pos, 0)
-			p := current
-			for p != start {
-				path = append(path, p)
-				p = cameFrom[p]
-			}
-			path = append(path, p)
-
-			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
-				path[i], path[j] = path[j], path[i]
-			}
-
-			return path
-		}
-
-		// second var after
-		for _, next := range getNeighbors(level, current) {
-			newCost := costSoFar[current] + 1 // always 1 for now
-			// The thing, "exist" will verify if it's there or not; blank shows we don't care what "the thing" is but that it exists
-			_, exists := costSoFar[next]
-			if !exists || newCost < costSoFar[next] {
-				costSoFar[next] = newCost
-				xDist := int(math.Abs(float64(goal.X - next.X)))
-				yDist := int(math.Abs(float64(goal.Y - next.Y)))
-				priority := newCost + xDist + yDist
-				frontier = frontier.push(next, priority)
-				cameFrom[next] = current
-
-			}
-		}
-	}
-	return nil
+// aStar finds the cheapest way from start to goal over the tiles walk allows
+func (level *Level) aStar(start Pos, goal Pos, walk func(*Level, Pos) bool) []Pos {
+	return pathfinding.AStar[Pos](levelGraph{level, walk}, start, goal)
 }
 
 // loads up game, called in main
 func (game *Game) Run() {
-	fmt.Println("Starting...")
-
 	count := 0
-	// go through all level channels and sent current game level
-	for _, lchan := range game.LevelChans {
-		lchan <- game.CurrentLevel
-	}
+	game.sendFrames()
 
 	// infinite loop to run as long as we need
 	for input := range game.InputChan {
@@ -748,22 +1068,30 @@ func (game *Game) Run() {
 			return
 		}
 
+		// opening and closing windows isn't a turn, only the window itself hears about it
+		switch input.Typ {
+		case OpenWindow:
+			game.openWindow(input.Window)
+			continue
+		case CloseWindow:
+			game.closeWindow(input.Window)
+			// all windows are closed, so quit
+			if len(game.Windows) == 0 {
+				return
+			}
+			continue
+		}
+
 		game.handleInput(input)
 
 		//game.Level.AddEvent("Move: " + strconv.Itoa(count))
 		count++
 
-		for _, monster := range game.CurrentLevel.Monsters {
-			monster.Update(game.CurrentLevel)
+		if !game.GameOver && takesTurn(input.Typ) {
+			game.endTurn()
 		}
 
-		// if number of level channels is 0, all windows are closed, so quit
-		if len(game.LevelChans) == 0 {
-			return
-		}
-		for _, lchan := range game.LevelChans {
-			lchan <- game.CurrentLevel
-		}
+		game.sendFrames()
 	}
 
 }
@@ -0,0 +1,395 @@
+package game
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// a walled room with the player in it
+const roomMap = `#####
+#@.B#
+#####
+`
+
+// mapDir is a maps directory with world.txt and a file for each name.map
+func mapDir(world string, files map[string]string) fstest.MapFS {
+	dir := fstest.MapFS{"world.txt": {Data: []byte(world)}}
+	for name, data := range files {
+		dir[name] = &fstest.MapFile{Data: []byte(data)}
+	}
+	return dir
+}
+
+func TestNewGame(t *testing.T) {
+	maps := mapDir("room\nroom,light,3\nroom,2,1,hall,1,1", map[string]string{
+		"room.map": roomMap,
+		"hall.map": "###\n#.#\n###\n",
+	})
+	g, err := NewGame(0, maps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	level := g.CurrentLevel
+	if level.Name != "room" || len(g.Levels) != 2 {
+		t.Fatalf("started on %q of %d levels, want room of 2", level.Name, len(g.Levels))
+	}
+	if level.Player.Pos != (Pos{1, 1}) {
+		t.Errorf("player at %v, want {1 1}", level.Player.Pos)
+	}
+	if level.Ambient != 3 {
+		t.Errorf("ambient light %d, want 3", level.Ambient)
+	}
+	// without monsters.txt and items.txt the built in ones are used
+	if m := level.Monsters[Pos{3, 1}]; m == nil || m.Name != "Bat" {
+		t.Errorf("monster at {3 1} is %v, want the built in Bat", m)
+	}
+	portal := level.Portals[Pos{2, 1}]
+	if portal == nil || portal.Level != g.Levels["hall"] || portal.Pos != (Pos{1, 1}) {
+		t.Errorf("portal at {2 1} is %v, want hall {1 1}", portal)
+	}
+	// the square under the player is filled in with the floor around it
+	if r := level.Map[1][1].Rune; r != DirtFloor {
+		t.Errorf("under the player is %q, want floor", r)
+	}
+}
+
+func TestNewGameDefaultMaps(t *testing.T) {
+	g, err := NewGame(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.CurrentLevel == nil || g.CurrentLevel.Name != "level1" {
+		t.Fatalf("started on %v, want level1", g.CurrentLevel)
+	}
+}
+
+func TestNewGameMonsterFiles(t *testing.T) {
+	maps := mapDir("room", map[string]string{
+		"room.map":     "####\n#@Z#\n####\n",
+		"monsters.txt": "Z, Zombie, 30, 4, 0.5, 6, Z\n",
+	})
+	g, err := NewGame(0, maps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := g.CurrentLevel.Monsters[Pos{2, 1}]; m == nil || m.Name != "Zombie" {
+		t.Errorf("monster at {2 1} is %v, want a Zombie from monsters.txt", m)
+	}
+
+	// a monsters.txt replaces the built in monsters rather than adding to them
+	maps["room.map"].Data = []byte("####\n#@B#\n####\n")
+	_, err = NewGame(0, maps)
+	var glyphErr *ErrUnknownGlyph
+	if !errors.As(err, &glyphErr) || glyphErr.Rune != 'B' {
+		t.Errorf("got %v, want B to be unknown", err)
+	}
+}
+
+// Glyphs can be any character, the tiles after one that takes more than a byte stay in
+// their columns
+func TestNewGameMultibyteGlyph(t *testing.T) {
+	maps := mapDir("room", map[string]string{
+		"room.map":     "######\n#@λ.λ#\n######\n",
+		"monsters.txt": "λ, Lich, 80, 6, 1, 8, L\n",
+	})
+	g, err := NewGame(0, maps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	level := g.CurrentLevel
+	if w := len(level.Map[0]); w != 6 {
+		t.Errorf("level is %d wide, want 6", w)
+	}
+	for _, x := range []int{2, 4} {
+		m := level.Monsters[Pos{x, 1}]
+		if m == nil || m.Name != "Lich" || m.Glyph != 'λ' {
+			t.Errorf("monster at {%d 1} is %v, want a Lich", x, m)
+		}
+	}
+	for x, want := range []rune{StoneWall, DirtFloor, DirtFloor, DirtFloor, DirtFloor, StoneWall} {
+		if r := level.Map[1][x].Rune; r != want {
+			t.Errorf("tile {%d 1} is %q, want %q", x, r, want)
+		}
+	}
+}
+
+// The game makes items from the items.txt its maps came with, glyphs of any width
+func TestNewGameItemFiles(t *testing.T) {
+	maps := mapDir("room", map[string]string{
+		"room.map":  "#####\n#@♛.#\n#####\n",
+		"items.txt": "crown, Crown, ♛, helmet, head, 0, Heavy with gold., , defense=3\n",
+	})
+	g, err := NewGame(0, maps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := g.CurrentLevel.Items[Pos{2, 1}]
+	if len(items) != 1 || items[0].ID != "crown" {
+		t.Fatalf("items at {2 1} are %v, want the crown", items)
+	}
+	if r := g.CurrentLevel.Map[1][3].Rune; r != DirtFloor {
+		t.Errorf("tile after the crown is %q, want floor", r)
+	}
+
+	crown, err := g.NewItem("crown", Pos{3, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crown.Slot != HeadSlot || crown.Modifiers.Defense != 3 || crown.Rune != '♛' {
+		t.Errorf("crown is %+v, want items.txt's", crown)
+	}
+	// an items.txt replaces the built in items
+	if _, err := g.NewItem("sword", Pos{3, 1}); err == nil {
+		t.Error("made the built in sword from a game with its own items")
+	}
+}
+
+func TestNewGameErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		maps fstest.MapFS
+		// part of the error message
+		want string
+	}{
+		{"no world file", fstest.MapFS{"room.map": {Data: []byte(roomMap)}}, "world.txt"},
+		{"no maps", mapDir("room", nil), "no .map files"},
+		{"empty map", mapDir("room", map[string]string{"room.map": ""}), "room.map: map is empty"},
+		{"empty world", mapDir("", map[string]string{"room.map": roomMap}), "world.txt: empty"},
+		{"light too bright", mapDir("room\nroom,light,99", map[string]string{"room.map": roomMap}), "world.txt:2: light should be"},
+		{"light not a number", mapDir("room\nroom,light,dim", map[string]string{"room.map": roomMap}), "world.txt:2: light should be"},
+		{"short portal", mapDir("room\nroom,2,1,room", map[string]string{"room.map": roomMap}), "world.txt:2: expected level,x,y,level,x,y"},
+		{"portal x", mapDir("room\nroom,two,1,room,1,1", map[string]string{"room.map": roomMap}), "world.txt:2: bad x"},
+		{"portal destination y", mapDir("room\nroom,2,1,room,1,one", map[string]string{"room.map": roomMap}), "world.txt:2: bad destination y"},
+		{"bad monsters file", mapDir("room", map[string]string{"room.map": roomMap, "monsters.txt": "B, Bat\n"}), "monsters.txt:1:"},
+		{"monster with an unknown item", mapDir("room", map[string]string{
+			"room.map":     roomMap,
+			"monsters.txt": "B, Bat, 50, 1, 1.5, 10, B, crown\n",
+		}), `unknown item "crown"`},
+	}
+	for _, tt := range tests {
+		_, err := NewGame(0, tt.maps)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error with %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestNewGameUnknownLevel(t *testing.T) {
+	tests := []struct {
+		world string
+		name  string
+		line  int
+	}{
+		{"cellar", "cellar", 1},
+		{"room\ncellar,light,2", "cellar", 2},
+		{"room\nroom,2,1,room,1,1\ncellar,2,1,room,1,1", "cellar", 3},
+		{"room\nroom,2,1,cellar,1,1", "cellar", 2},
+	}
+	for _, tt := range tests {
+		_, err := NewGame(0, mapDir(tt.world, map[string]string{"room.map": roomMap}))
+		var levelErr *ErrUnknownLevel
+		if !errors.As(err, &levelErr) || levelErr.Name != tt.name || levelErr.WorldLine != tt.line {
+			t.Errorf("%q: got %v, want unknown level %s on line %d", tt.world, err, tt.name, tt.line)
+		}
+	}
+}
+
+// Every bad character in a map is reported, where an editor would show it
+func TestNewGameUnknownGlyph(t *testing.T) {
+	maps := mapDir("room", map[string]string{"room.map": "#####\n#@.%#\n#?^.#\n#é%.#\n#####\n"})
+	_, err := NewGame(0, maps)
+	if err == nil {
+		t.Fatal("loaded a map with unknown characters")
+	}
+	var got []ErrUnknownGlyph
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var glyphErr *ErrUnknownGlyph
+		if !errors.As(e, &glyphErr) {
+			t.Fatalf("%v isn't an unknown glyph", e)
+		}
+		got = append(got, *glyphErr)
+	}
+	// ? is a tonic, it's only %, ^ and é nothing knows. Columns count characters, é is
+	// two bytes but one column
+	want := []ErrUnknownGlyph{
+		{"room.map", 2, 4, '%'},
+		{"room.map", 3, 3, '^'},
+		{"room.map", 4, 2, 'é'},
+		{"room.map", 4, 3, '%'},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("error %d is %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+// fighter is a character with nothing equipped that swings for attack
+func fighter(name string, attack, accuracy, evasion int) *Character {
+	return &Character{Entity: Entity{Name: name}, Hitpoints: 1 << 30, MaxHitpoints: 1 << 30, Strength: attack, Speed: 1, SightRange: 5, Accuracy: accuracy, Evasion: evasion}
+}
+
+// Hit chances stay between 5 and 95 percent however lopsided the fight
+func TestAttackHitChance(t *testing.T) {
+	tests := []struct {
+		name              string
+		accuracy, evasion int
+		minHits, maxHits  int
+	}{
+		{"hopeless", 0, 100, 30, 70},
+		{"even", 60, 10, 450, 550},
+		{"certain", 200, 0, 930, 970},
+	}
+	for _, tt := range tests {
+		level := testRoom(5, 5)
+		level.Seed(7)
+		a, d := fighter("a", 10, tt.accuracy, 0), fighter("d", 1, 0, tt.evasion)
+		hits := 0
+		for i := 0; i < 1000; i++ {
+			if level.Attack(a, d, Pos{2, 2}).Hit {
+				hits++
+			}
+		}
+		if hits < tt.minHits || hits > tt.maxHits {
+			t.Errorf("%s: %d hits in 1000, want %d to %d", tt.name, hits, tt.minHits, tt.maxHits)
+		}
+	}
+}
+
+// Damage is the attack give or take a quarter, crits double it and every 10 defense
+// takes another share off, a hit never does less than 1
+func TestAttackDamage(t *testing.T) {
+	helmet := &Item{Slot: HeadSlot, Modifiers: Stats{Defense: 10}}
+	tests := []struct {
+		name      string
+		attack    int
+		helmet    *Item
+		low, high int
+	}{
+		{"bare", 20, nil, 15, 25},
+		{"halved by defense", 20, helmet, 7, 12},
+		{"weak", 1, helmet, 1, 1},
+	}
+	for _, tt := range tests {
+		level := testRoom(5, 5)
+		level.Seed(7)
+		a, d := fighter("a", tt.attack, 1000, 0), fighter("d", 1, 0, 0)
+		d.Helmet = tt.helmet
+		hits, crits := 0, 0
+		for i := 0; i < 1000; i++ {
+			before := d.Hitpoints
+			r := level.Attack(a, d, Pos{2, 2})
+			if !r.Hit {
+				continue
+			}
+			hits++
+			low, high := tt.low, tt.high
+			if r.Crit {
+				crits++
+				low, high = tt.attack*3/2*10/(10+d.Stats().Defense), tt.attack*5/2*10/(10+d.Stats().Defense)
+				if low < 1 {
+					low = 1
+				}
+			}
+			if r.Damage < low || r.Damage > high || before-d.Hitpoints != r.Damage {
+				t.Fatalf("%s: %+v took %d, want %d to %d", tt.name, r, before-d.Hitpoints, low, high)
+			}
+		}
+		if hits == 0 || crits == 0 {
+			t.Errorf("%s: %d hits and %d crits in 1000 swings", tt.name, hits, crits)
+		}
+	}
+}
+
+// The same seed rolls the same fight
+func TestAttackSeeded(t *testing.T) {
+	fight := func() []AttackResult {
+		level := testRoom(5, 5)
+		level.Seed(42)
+		a, d := fighter("a", 12, 70, 0), fighter("d", 1, 0, 20)
+		d.Hitpoints = 100
+		var results []AttackResult
+		for !(len(results) > 0 && results[len(results)-1].Killed) {
+			results = append(results, level.Attack(a, d, Pos{2, 2}))
+		}
+		return results
+	}
+	first, second := fight(), fight()
+	if len(first) != len(second) {
+		t.Fatalf("%d swings then %d", len(first), len(second))
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("swing %d: %+v then %+v", i, first[i], second[i])
+		}
+	}
+}
+
+// Killing a monster takes it off the level, its things drop where it stood
+func TestAttackKills(t *testing.T) {
+	level := testRoom(5, 5)
+	g := NewGameFromLevel(0, func() *Level { return level })
+	m := testMonster(level, Pos{2, 1}, 1)
+	m.Hitpoints = 1
+	m.Evasion = 0
+	m.Items = []*Item{NewPotion(m.Pos)}
+	level.Player.Accuracy = 1000
+	g.resolveMovement(m.Pos)
+	if level.Monsters[m.Pos] != nil || !level.Happened(Kill) {
+		t.Fatal("the monster survived")
+	}
+	if len(level.Items[m.Pos]) != 1 {
+		t.Errorf("dropped %v, want the potion", level.Items[m.Pos])
+	}
+}
+
+// A ring goes on the hand it's put on, left to the game it fills an empty one first
+func TestEquipRing(t *testing.T) {
+	tests := []struct {
+		name         string
+		ring1, ring2 bool
+		ring         int
+		// what's on each hand after and what went back in the bag, "" for nothing
+		want1, want2, bag string
+	}{
+		{"bare hands", false, false, 0, "new", "", ""},
+		{"first hand taken", true, false, 0, "old1", "new", ""},
+		{"both taken", true, true, 0, "new", "old2", "old1"},
+		{"onto the second", true, true, 2, "old1", "new", "old2"},
+		{"onto the empty first", false, true, 1, "new", "old2", ""},
+		{"second asked for", false, false, 2, "", "new", ""},
+	}
+	name := func(item *Item) string {
+		if item == nil {
+			return ""
+		}
+		return item.Name
+	}
+	for _, tt := range tests {
+		level := testRoom(5, 5)
+		c := &level.Player.Character
+		if tt.ring1 {
+			c.Ring1 = &Item{Entity: Entity{Name: "old1"}, Slot: RingSlot}
+		}
+		if tt.ring2 {
+			c.Ring2 = &Item{Entity: Entity{Name: "old2"}, Slot: RingSlot}
+		}
+		ring := &Item{Entity: Entity{Name: "new"}, Slot: RingSlot}
+		c.Items = []*Item{ring}
+		level.EquipItem(ring, c, tt.ring)
+
+		bag := ""
+		for _, item := range c.Items {
+			bag += item.Name
+		}
+		if name(c.Ring1) != tt.want1 || name(c.Ring2) != tt.want2 || bag != tt.bag {
+			t.Errorf("%s: wearing %q and %q with %q in the bag, want %q and %q with %q", tt.name,
+				name(c.Ring1), name(c.Ring2), bag, tt.want1, tt.want2, tt.bag)
+		}
+	}
+}
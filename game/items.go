@@ -1,26 +1,340 @@
 package game
 
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
 type ItemType int
 
 const (
 	Weapon ItemType = iota
 	Helmet
 	Other
+	Treasure
+	Consumable
+	Armor
+	Jewelry
 )
 
+var itemTypeNames = map[string]ItemType{
+	"weapon":     Weapon,
+	"helmet":     Helmet,
+	"other":      Other,
+	"treasure":   Treasure,
+	"consumable": Consumable,
+	"armor":      Armor,
+	"jewelry":    Jewelry,
+}
+
+// Effect is what a consumable does when it's used, power says how much
+type Effect int
+
+const (
+	NoEffect Effect = iota
+	Heal
+	GainStrength
+)
+
+var effectNames = map[string]Effect{
+	"":         NoEffect,
+	"heal":     Heal,
+	"strength": GainStrength,
+}
+
+// Slot is where an item goes when it's equipped, a character has two ring slots
+type Slot int
+
+const (
+	NoSlot Slot = iota
+	HeadSlot
+	WeaponSlot
+	BodySlot
+	FeetSlot
+	ShieldSlot
+	NeckSlot
+	RingSlot
+)
+
+var slotNames = map[string]Slot{
+	"":       NoSlot,
+	"head":   HeadSlot,
+	"weapon": WeaponSlot,
+	"body":   BodySlot,
+	"feet":   FeetSlot,
+	"shield": ShieldSlot,
+	"neck":   NeckSlot,
+	"ring":   RingSlot,
+}
+
+// Stats are the numbers combat and movement use. A character's come from its
+// base values plus the Modifiers of everything it has equipped
+type Stats struct {
+	Attack       int
+	Defense      int
+	Speed        float64
+	SightRange   int
+	MaxHitpoints int
+	// percent chance to hit before the target's evasion is taken off
+	Accuracy int
+	Evasion  int
+	// how far the light its bearer gives off reaches
+	Light int
+}
+
+func (s Stats) add(o Stats) Stats {
+	return Stats{
+		Attack:       s.Attack + o.Attack,
+		Defense:      s.Defense + o.Defense,
+		Speed:        s.Speed + o.Speed,
+		SightRange:   s.SightRange + o.SightRange,
+		MaxHitpoints: s.MaxHitpoints + o.MaxHitpoints,
+		Accuracy:     s.Accuracy + o.Accuracy,
+		Evasion:      s.Evasion + o.Evasion,
+		Light:        s.Light + o.Light,
+	}
+}
+
+// parses the modifiers column, space separated name=value pairs like "attack=5 speed=-0.1"
+func parseModifiers(field string) (Stats, error) {
+	var mods Stats
+	for _, pair := range strings.Fields(field) {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return mods, fmt.Errorf("modifier %q should look like name=value", pair)
+		}
+		var err error
+		switch strings.ToLower(name) {
+		case "attack":
+			mods.Attack, err = strconv.Atoi(value)
+		case "defense":
+			mods.Defense, err = strconv.Atoi(value)
+		case "speed":
+			mods.Speed, err = strconv.ParseFloat(value, 64)
+		case "sight":
+			mods.SightRange, err = strconv.Atoi(value)
+		case "hp":
+			mods.MaxHitpoints, err = strconv.Atoi(value)
+		case "accuracy":
+			mods.Accuracy, err = strconv.Atoi(value)
+		case "evasion":
+			mods.Evasion, err = strconv.Atoi(value)
+		case "light":
+			mods.Light, err = strconv.Atoi(value)
+		default:
+			return mods, fmt.Errorf("unknown modifier %q, expected attack, defense, speed, sight, hp, accuracy, evasion or light", name)
+		}
+		if err != nil {
+			return mods, fmt.Errorf("bad %s modifier: %w", name, err)
+		}
+	}
+	return mods, nil
+}
+
 type Item struct {
 	Typ ItemType
 	// pos, name, rune
 	Entity
 	power float64
+
+	// catalog id, empty for items that didn't come from the catalog
+	ID          string
+	Slot        Slot
+	Effect      Effect
+	Description string
+	// added to the stats of whoever has it equipped
+	Modifiers Stats
+}
+
+// ItemDef is one line of items.txt
+type ItemDef struct {
+	ID          string
+	Name        string
+	Glyph       rune // used both in .map files and as the sprite in atlas-index.txt
+	Typ         ItemType
+	Slot        Slot
+	Power       float64
+	Description string
+	Effect      Effect
+	Modifiers   Stats
+}
+
+// ItemCatalog is keyed by id
+type ItemCatalog map[string]*ItemDef
+
+const itemsFile = "items.txt"
+
+var defaultItemCatalog = mustLoadDefaultItemCatalog()
+
+func mustLoadDefaultItemCatalog() ItemCatalog {
+	file, err := embeddedMaps.Open("maps/" + itemsFile)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	catalog, err := ParseItemCatalog(file)
+	if err != nil {
+		panic(err)
+	}
+	return catalog
+}
+
+// DefaultItemCatalog is the items that ship with the game
+func DefaultItemCatalog() ItemCatalog {
+	return defaultItemCatalog
+}
+
+// LoadItemCatalog reads items.txt from maps, falling back to the built in
+// items when maps doesn't have one
+func LoadItemCatalog(maps fs.FS) (ItemCatalog, error) {
+	file, err := maps.Open(itemsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return defaultItemCatalog, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseItemCatalog(file)
+}
+
+// ParseItemCatalog reads the items.txt format, lines starting with # are comments:
+// id, name, glyph, type, slot, power, description, effect, modifiers
+// effect is only needed for consumables and modifiers only for things that can be equipped
+func ParseItemCatalog(r io.Reader) (ItemCatalog, error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+	csvReader.Comment = '#'
+
+	catalog := make(ItemCatalog)
+	glyphs := make(map[rune]string)
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", itemsFile, err)
+		}
+		line, _ := csvReader.FieldPos(0)
+		if len(row) < 7 || len(row) > 9 {
+			return nil, fmt.Errorf("%s:%d: expected id, name, glyph, type, slot, power, description and an optional effect and modifiers but got %d fields", itemsFile, line, len(row))
+		}
+
+		def := &ItemDef{ID: row[0], Name: row[1], Description: row[6]}
+		if catalog[def.ID] != nil {
+			return nil, fmt.Errorf("%s:%d: id %q is used twice", itemsFile, line, def.ID)
+		}
+		var ok bool
+		def.Glyph, ok = parseRune(row[2])
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: glyph %q should be a single character", itemsFile, line, row[2])
+		}
+		if strings.ContainsRune(reservedGlyphs, def.Glyph) {
+			return nil, fmt.Errorf("%s:%d: glyph %q is already used by the map loader", itemsFile, line, def.Glyph)
+		}
+		if glyphs[def.Glyph] != "" {
+			return nil, fmt.Errorf("%s:%d: glyph %q is already used by %s", itemsFile, line, def.Glyph, glyphs[def.Glyph])
+		}
+		def.Typ, ok = itemTypeNames[strings.ToLower(row[3])]
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: unknown item type %q", itemsFile, line, row[3])
+		}
+		def.Slot, ok = slotNames[strings.ToLower(row[4])]
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: unknown slot %q", itemsFile, line, row[4])
+		}
+		def.Power, err = strconv.ParseFloat(row[5], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad power: %w", itemsFile, line, err)
+		}
+		if len(row) >= 8 {
+			def.Effect, ok = effectNames[strings.ToLower(row[7])]
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: unknown effect %q", itemsFile, line, row[7])
+			}
+		}
+		if (def.Typ == Consumable) != (def.Effect != NoEffect) {
+			return nil, fmt.Errorf("%s:%d: consumables need an effect and only consumables can have one", itemsFile, line)
+		}
+		if len(row) == 9 {
+			def.Modifiers, err = parseModifiers(row[8])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", itemsFile, line, err)
+			}
+			if def.Slot == NoSlot && def.Modifiers != (Stats{}) {
+				return nil, fmt.Errorf("%s:%d: modifiers only work on items with a slot", itemsFile, line)
+			}
+		}
+		catalog[def.ID] = def
+		glyphs[def.Glyph] = def.ID
+	}
+	return catalog, nil
+}
+
+// ByGlyph finds the item a map character places, nil if there isn't one
+func (catalog ItemCatalog) ByGlyph(glyph rune) *ItemDef {
+	for _, def := range catalog {
+		if def.Glyph == glyph {
+			return def
+		}
+	}
+	return nil
+}
+
+func (def *ItemDef) spawn(p Pos) *Item {
+	return &Item{
+		Typ:         def.Typ,
+		Entity:      Entity{p, def.Name, def.Glyph},
+		power:       def.Power,
+		ID:          def.ID,
+		Slot:        def.Slot,
+		Effect:      def.Effect,
+		Description: def.Description,
+		Modifiers:   def.Modifiers,
+	}
+}
+
+// New makes an item from the catalog
+func (catalog ItemCatalog) New(id string, p Pos) (*Item, error) {
+	def := catalog[id]
+	if def == nil {
+		return nil, fmt.Errorf("no item with id %q", id)
+	}
+	return def.spawn(p), nil
+}
+
+// NewItem makes any item in the catalog the game was loaded with, the maps' own
+// items.txt when they have one
+func (game *Game) NewItem(id string, p Pos) (*Item, error) {
+	return game.items.New(id, p)
+}
+
+// mustNewItem makes a built in item
+func mustNewItem(id string, p Pos) *Item {
+	item, err := defaultItemCatalog.New(id, p)
+	if err != nil {
+		panic(err)
+	}
+	return item
 }
 
 func NewSword(p Pos) *Item {
-	return &Item{Weapon, Entity{p, "Sword", 's'}, 2.0}
+	return mustNewItem("sword", p)
 }
 
 func NewHelmet(p Pos) *Item {
-	return &Item{Helmet, Entity{p, "Helmet", 'h'}, .5}
+	return mustNewItem("helmet", p)
+}
+
+func NewPotion(p Pos) *Item {
+	return mustNewItem("potion", p)
 }
 
 // inspired by Jack Mott on Youtube's GamewithGo series
@@ -0,0 +1,84 @@
+package game
+
+import "math"
+
+// Light runs from 0, pitch dark, up to MaxLight. Every level has an Ambient light that's
+// everywhere, MaxLight unless world.txt says otherwise, and light sources add to it around
+// themselves: torches fixed to walls, whatever the player has equipped that gives light and
+// monsters that glow. A source's light fades with distance and walls, closed doors and deep
+// grass cast shadows from it the same way they block sight. The player can only see lit
+// tiles, besides the ones right next to them which they can feel their way around
+const MaxLight = 8
+
+// WallTorch is a torch fixed to a wall, maps put it where the wall goes
+const WallTorch rune = 't'
+
+// how far a wall torch reaches
+const torchRadius = 6
+
+// how far away the player can make things out with no light at all
+const darkSight = 1
+
+// LightAt is how brightly lit pos is
+func (level *Level) LightAt(pos Pos) int {
+	light := level.Ambient + level.Map[pos.Y][pos.X].Light
+	if light > MaxLight {
+		return MaxLight
+	}
+	return light
+}
+
+// whether the player at from can make out what's at pos, if nothing is in the way
+func (level *Level) canMakeOut(from, pos Pos) bool {
+	return level.LightAt(pos) > 0 || (abs(pos.X-from.X) <= darkSight && abs(pos.Y-from.Y) <= darkSight)
+}
+
+// relight works out every tile's Light from the light sources. Only the tiles lit last
+// time are cleared so it costs the sources' reach, not the map
+func (level *Level) relight() {
+	for _, pos := range level.lit {
+		level.Map[pos.Y][pos.X].Light = 0
+	}
+	level.lit = level.lit[:0]
+
+	if level.torches == nil {
+		level.torches = make([]Pos, 0)
+		for y, row := range level.Map {
+			for x, tile := range row {
+				if tile.OverlayRune == WallTorch {
+					level.torches = append(level.torches, Pos{x, y})
+				}
+			}
+		}
+	}
+	for _, pos := range level.torches {
+		level.shine(pos, torchRadius)
+	}
+	level.shine(level.Player.Pos, level.Player.Stats().Light)
+	for pos, m := range level.Monsters {
+		level.shine(pos, m.Stats().Light)
+	}
+}
+
+// shine adds the light of a source at pos that reaches radius tiles, brightest where
+// it stands and fading to nothing just past radius
+func (level *Level) shine(pos Pos, radius int) {
+	if radius <= 0 {
+		return
+	}
+	level.fov().Compute(level, pos, radius, func(p Pos) {
+		dx, dy := float64(p.X-pos.X), float64(p.Y-pos.Y)
+		amount := int(math.Round(MaxLight * (1 - math.Sqrt(dx*dx+dy*dy)/float64(radius+1))))
+		if amount <= 0 {
+			return
+		}
+		tile := &level.Map[p.Y][p.X]
+		if tile.Light == 0 {
+			level.lit = append(level.lit, p)
+		}
+		tile.Light += amount
+		if tile.Light > MaxLight {
+			tile.Light = MaxLight
+		}
+	})
+}
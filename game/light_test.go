@@ -0,0 +1,101 @@
+package game
+
+import "testing"
+
+// a dark room with a torch on the middle of its west wall
+func torchRoom() *Level {
+	level := testRoom(15, 9)
+	level.Ambient = 0
+	level.Map[4][0].OverlayRune = WallTorch
+	level.Player.Pos = Pos{13, 7}
+	return level
+}
+
+// A torch is brightest beside it and fades to nothing just past its reach
+func TestTorchFalloff(t *testing.T) {
+	level := torchRoom()
+	level.relight()
+	want := []int{7, 6, 5, 3, 2, 1, 0, 0}
+	for i, w := range want {
+		pos := Pos{1 + i, 4}
+		if got := level.LightAt(pos); got != w {
+			t.Errorf("%d from the torch: light %d, want %d", i+1, got, w)
+		}
+	}
+	// the same distance is as bright in any direction
+	if level.LightAt(Pos{3, 1}) != level.LightAt(Pos{3, 7}) {
+		t.Errorf("light %d above the torch's row and %d below", level.LightAt(Pos{3, 1}), level.LightAt(Pos{3, 7}))
+	}
+}
+
+// Walls cast shadows from a torch the way they block sight
+func TestTorchShadow(t *testing.T) {
+	level := torchRoom()
+	level.Map[4][2].Rune = StoneWall
+	level.relight()
+	if level.LightAt(Pos{1, 4}) != 7 {
+		t.Errorf("light %d in front of the wall, want 7", level.LightAt(Pos{1, 4}))
+	}
+	for x := 3; x <= 5; x++ {
+		if light := level.LightAt(Pos{x, 4}); light != 0 {
+			t.Errorf("light %d at %d behind the wall, want 0", light, x)
+		}
+	}
+}
+
+// Ambient light is everywhere, sources add to it up to MaxLight
+func TestAmbientLight(t *testing.T) {
+	level := torchRoom()
+	level.Ambient = 3
+	level.Map[4][0].OverlayRune = Blank
+	level.relight()
+	for y := 1; y < 8; y++ {
+		for x := 1; x < 14; x++ {
+			if light := level.LightAt(Pos{x, y}); light != 3 {
+				t.Fatalf("light %d at %d,%d with no sources, want the ambient 3", light, x, y)
+			}
+		}
+	}
+
+	level = torchRoom()
+	level.Ambient = 3
+	level.relight()
+	if light := level.LightAt(Pos{1, 4}); light != MaxLight {
+		t.Errorf("light %d beside the torch, want it capped at %d", light, MaxLight)
+	}
+	if light := level.LightAt(Pos{4, 4}); light != 6 {
+		t.Errorf("light %d 4 from the torch, want its 3 on the ambient 3", light)
+	}
+}
+
+// Light the player carries goes with them, and in the dark they only see what's lit and
+// what's right next to them
+func TestCarriedLight(t *testing.T) {
+	level := torchRoom()
+	level.Map[4][0].OverlayRune = Blank
+	level.Player.SightRange = 10
+	level.Player.Helmet = &Item{Entity: Entity{Name: "Lamp"}, Slot: HeadSlot, Modifiers: Stats{Light: 2}}
+	level.Player.Pos = Pos{3, 4}
+	level.refreshSight()
+	if level.LightAt(Pos{3, 4}) != MaxLight || level.LightAt(Pos{5, 4}) != 3 || level.LightAt(Pos{6, 4}) != 0 {
+		t.Errorf("lamp lights %d, %d and %d at 0, 2 and 3 away, want %d, 3 and 0",
+			level.LightAt(Pos{3, 4}), level.LightAt(Pos{5, 4}), level.LightAt(Pos{6, 4}), MaxLight)
+	}
+	if !level.Map[4][5].Visible || level.Map[4][6].Visible {
+		t.Error("the player sees what isn't lit or misses what is")
+	}
+
+	level.Player.Helmet = nil
+	level.refreshSight()
+	if level.LightAt(Pos{3, 4}) != 0 {
+		t.Errorf("light %d where the lamp was, want 0", level.LightAt(Pos{3, 4}))
+	}
+	for y := 1; y < 8; y++ {
+		for x := 1; x < 14; x++ {
+			near := abs(x-3) <= darkSight && abs(y-4) <= darkSight
+			if level.Map[y][x].Visible != near {
+				t.Errorf("%d,%d visible %v in the dark, want %v", x, y, level.Map[y][x].Visible, near)
+			}
+		}
+	}
+}
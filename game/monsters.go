@@ -1,25 +1,236 @@
 package game
 
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
 type Monster struct {
 	Pos
 	Rune rune
+	// the map glyph it was placed with, what its MonsterDef is keyed by
+	Glyph rune
 	Character
+
+	State    AIState
+	Behavior Behavior
+	// where the player was when the monster last saw them
+	LastSeen   Pos
+	searchLeft int
 }
 
-// as opposed to Rat in Jack's video
-func NewBat(p Pos) *Monster {
+// MonsterDef is one line of monsters.txt, everything needed to place a monster from a map glyph
+type MonsterDef struct {
+	Glyph      rune
+	Name       string
+	Hitpoints  int
+	Strength   int
+	Speed      float64
+	SightRange int
+	Accuracy   int
+	Evasion    int
+	Swims      bool
+	Light      int
+	Behavior   Behavior
+	// the atlas-index.txt entry the ui draws it with
+	Sprite rune
+	// item catalog ids
+	Items []string
+}
+
+// MonsterDefs are keyed by map glyph
+type MonsterDefs map[rune]*MonsterDef
+
+const monstersFile = "monsters.txt"
+
+// for monsters that don't set accuracy or evasion, see defaultBehavior for the ai
+const (
+	defaultAccuracy = 75
+	defaultEvasion  = 5
+)
+
+// glyphs the map loader already uses for terrain and the player
+const reservedGlyphs = " \t\r#|/ud.@~=\"_t"
+
+var defaultMonsterDefs = mustLoadDefaultMonsterDefs()
+
+func mustLoadDefaultMonsterDefs() MonsterDefs {
+	file, err := embeddedMaps.Open("maps/" + monstersFile)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	defs, err := ParseMonsterDefs(file)
+	if err != nil {
+		panic(err)
+	}
+	return defs
+}
+
+// LoadMonsterDefs reads monsters.txt from maps, falling back to the built in
+// monsters when maps doesn't have one
+func LoadMonsterDefs(maps fs.FS) (MonsterDefs, error) {
+	file, err := maps.Open(monstersFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return defaultMonsterDefs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseMonsterDefs(file)
+}
+
+func parseRune(field string) (rune, bool) {
+	r, size := utf8.DecodeRuneInString(field)
+	return r, r != utf8.RuneError && size == len(field)
+}
+
+// ParseMonsterDefs reads the monsters.txt format, lines starting with # are comments:
+// glyph, name, hitpoints, strength, speed, sight range, sprite, starting items...
+// accuracy=n, evasion=n, swim=true, wander=n, flee=n, search=n, doors=true and light=n can go anywhere
+// among the starting items
+func ParseMonsterDefs(r io.Reader) (MonsterDefs, error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+	csvReader.Comment = '#'
+
+	defs := make(MonsterDefs)
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", monstersFile, err)
+		}
+		line, _ := csvReader.FieldPos(0)
+		if len(row) < 7 {
+			return nil, fmt.Errorf("%s:%d: expected glyph, name, hitpoints, strength, speed, sight range, sprite but got %d fields", monstersFile, line, len(row))
+		}
+
+		def := &MonsterDef{Name: row[1], Accuracy: defaultAccuracy, Evasion: defaultEvasion, Behavior: defaultBehavior}
+		var ok bool
+		def.Glyph, ok = parseRune(row[0])
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: glyph %q should be a single character", monstersFile, line, row[0])
+		}
+		if strings.ContainsRune(reservedGlyphs, def.Glyph) {
+			return nil, fmt.Errorf("%s:%d: glyph %q is already used by the map loader", monstersFile, line, def.Glyph)
+		}
+		if defs[def.Glyph] != nil {
+			return nil, fmt.Errorf("%s:%d: glyph %q is already used by %s", monstersFile, line, def.Glyph, defs[def.Glyph].Name)
+		}
+		def.Hitpoints, err = strconv.Atoi(row[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad hitpoints: %w", monstersFile, line, err)
+		}
+		def.Strength, err = strconv.Atoi(row[3])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad strength: %w", monstersFile, line, err)
+		}
+		def.Speed, err = strconv.ParseFloat(row[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad speed: %w", monstersFile, line, err)
+		}
+		def.SightRange, err = strconv.Atoi(row[5])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad sight range: %w", monstersFile, line, err)
+		}
+		def.Sprite, ok = parseRune(row[6])
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: sprite %q should be a single character", monstersFile, line, row[6])
+		}
+		for _, field := range row[7:] {
+			name, value, ok := strings.Cut(field, "=")
+			if !ok {
+				// item ids are checked against the catalog once both files are loaded
+				def.Items = append(def.Items, field)
+				continue
+			}
+			switch strings.ToLower(name) {
+			case "accuracy":
+				def.Accuracy, err = strconv.Atoi(value)
+			case "evasion":
+				def.Evasion, err = strconv.Atoi(value)
+			case "swim":
+				def.Swims, err = strconv.ParseBool(value)
+			case "wander":
+				def.Behavior.WanderChance, err = strconv.Atoi(value)
+			case "flee":
+				def.Behavior.FleeAt, err = strconv.Atoi(value)
+			case "search":
+				def.Behavior.SearchTurns, err = strconv.Atoi(value)
+			case "doors":
+				def.Behavior.OpensDoors, err = strconv.ParseBool(value)
+			case "light":
+				def.Light, err = strconv.Atoi(value)
+			default:
+				return nil, fmt.Errorf("%s:%d: unknown stat %q, expected accuracy, evasion, swim, wander, flee, search, doors or light", monstersFile, line, name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: bad %s: %w", monstersFile, line, name, err)
+			}
+		}
+		defs[def.Glyph] = def
+	}
+	return defs, nil
+}
+
+// makes sure every starting item is in the catalog and no glyph means both a monster and an item
+func (defs MonsterDefs) check(catalog ItemCatalog) error {
+	for _, def := range defs {
+		for _, id := range def.Items {
+			if catalog[id] == nil {
+				return fmt.Errorf("%s: %s starts with unknown item %q", monstersFile, def.Name, id)
+			}
+		}
+		item := catalog.ByGlyph(def.Glyph)
+		if item != nil {
+			return fmt.Errorf("%s: glyph %q is used by both %s and item %s", monstersFile, def.Glyph, def.Name, item.ID)
+		}
+	}
+	return nil
+}
+
+func (def *MonsterDef) spawn(p Pos, catalog ItemCatalog) *Monster {
 	monster := &Monster{}
 	monster.Pos = p
-	monster.Rune = 'B'
-	monster.Name = "Bat"
-	monster.Hitpoints = 50
-	monster.Strength = 1
-	monster.Speed = 1.5
+	monster.Rune = def.Sprite
+	monster.Glyph = def.Glyph
+	monster.Name = def.Name
+	monster.Hitpoints = def.Hitpoints
+	monster.MaxHitpoints = def.Hitpoints
+	monster.Strength = def.Strength
+	monster.Speed = def.Speed
 	monster.ActionPoints = 0.0
-	monster.SightRange = 10
+	monster.SightRange = def.SightRange
+	monster.Accuracy = def.Accuracy
+	monster.Evasion = def.Evasion
+	monster.Swims = def.Swims
+	monster.Light = def.Light
+	monster.Behavior = def.Behavior
+	for _, id := range def.Items {
+		item, err := catalog.New(id, p)
+		if err == nil {
+			monster.Items = append(monster.Items, item)
+		}
+	}
 	return monster
 }
 
+// as opposed to Rat in Jack's video
+func NewBat(p Pos) *Monster {
+	return defaultMonsterDefs['B'].spawn(p, defaultItemCatalog)
+}
+
 func (m *Monster) Kill(level *Level) {
 	delete(level.Monsters, m.Pos)
 	groundItems := level.Items[m.Pos]
@@ -31,83 +242,71 @@ func (m *Monster) Kill(level *Level) {
 }
 
 func NewSpider(p Pos) *Monster {
-	//return &Monster{p, 'S', "Spider", 10, 10, 1.0, 0.0}
-	monster := &Monster{}
-	monster.Pos = p
-	monster.Rune = 'S'
-	monster.Name = "Spider"
-	monster.Hitpoints = 100
-	monster.Strength = 5
-	monster.Speed = 1.1
-	monster.ActionPoints = 0.0
-	monster.SightRange = 10
-	return monster
+	return defaultMonsterDefs['S'].spawn(p, defaultItemCatalog)
 }
 
 // New Boss monster added
 func NewDragon(p Pos) *Monster {
-	monster := &Monster{}
-	monster.Pos = p
-	monster.Rune = 'D'
-	monster.Name = "Dragon"
-	monster.Hitpoints = 300
-	monster.Strength = 100
-	monster.Speed = 0.8
-	monster.ActionPoints = 0.0
-	monster.SightRange = 5
-	return monster
+	return defaultMonsterDefs['D'].spawn(p, defaultItemCatalog)
 }
 
+// Update is one action, a step, an attack or a wait, paid for out of the monster's
+// ActionPoints. The scheduler decides when it's the monster's turn
 func (m *Monster) Update(level *Level) {
-	m.ActionPoints += m.Speed
-	playerPos := level.Player.Pos
-	apInt := int(m.ActionPoints)
-	positions := level.aStar(m.Pos, playerPos)
-
-	// do we have any path to the goal?
-	if len(positions) == 0 {
-		m.Pass()
+	// nothing left to chase
+	if level.Player.Hitpoints <= 0 {
 		return
 	}
+	// a turn spent standing in lava
+	level.burnMonster(m)
+	if m.Hitpoints <= 0 {
+		return
+	}
+	m.think(level)
+	positions := m.plan(level, 1)
 
-	moveIndex := 1
-
-	for i := 0; i < apInt; i++ {
-		if moveIndex < len(positions) {
-			m.Move(positions[moveIndex], level)
-			moveIndex++
-			m.ActionPoints--
-		}
+	// idle, or nowhere to go
+	if len(positions) < 2 {
+		m.Pass()
+		return
 	}
+	m.ActionPoints -= float64(m.Move(positions[1], level))
 }
 
+// Pass spends a turn doing nothing
 func (m *Monster) Pass() {
-	m.ActionPoints -= m.Speed
+	m.ActionPoints -= actionCost
 }
 
-func (m *Monster) Move(to Pos, level *Level) {
+// how m finds its way, see mover
+func (m *Monster) mover() mover {
+	return mover{swims: m.Swims, opensDoors: m.Behavior.OpensDoors}
+}
+
+// Move has m step to to, or attack the player if they're there, and is how many turns
+// that took. Wading in takes two, anything else one
+func (m *Monster) Move(to Pos, level *Level) int {
+	// monsters that open doors spend the step doing it
+	if m.Behavior.OpensDoors && level.openDoor(m.Name, to) {
+		return actionCost
+	}
 	_, exists := level.Monsters[to]
 
 	// if there's a monster/player in the way
 	if !exists && to != level.Player.Pos {
+		to = level.slide(m.Pos, to, m.walkable)
 		delete(level.Monsters, m.Pos)
 		level.Monsters[to] = m
 		m.Pos = to
-		return
+		level.burnMonster(m)
+		return moveTurns(level, to)
 	}
 
+	// the player dying is seen to at the end of the turn, see endTurn
 	if to == level.Player.Pos {
-		level.AddEvent(m.Name + " Attacks Player")
-		level.Attack(&m.Character, &level.Player.Character)
-		if m.Hitpoints <= 0 {
-			delete(level.Monsters, m.Pos)
-		}
-		if level.Player.Hitpoints <= 0 {
-			//level.AddEvent("You have died")
-			panic("ded")
-		}
+		level.Attack(&m.Character, &level.Player.Character, to)
 	}
-
+	return actionCost
 }
 
 // inspired by Jack Mott on Youtube's GamewithGo series
@@ -0,0 +1,132 @@
+package game
+
+// the four straight steps in the order neighbors have always been tried in, then the diagonals
+var directions = []Pos{
+	{1, 0}, {-1, 0}, {0, -1}, {0, 1},
+	{-1, -1}, {1, -1}, {-1, 1}, {1, 1},
+}
+
+// the step each movement input takes
+var moveDirections = map[InputType]Pos{
+	Up:        {0, -1},
+	Down:      {0, 1},
+	Left:      {-1, 0},
+	Right:     {1, 0},
+	UpLeft:    {-1, -1},
+	UpRight:   {1, -1},
+	DownLeft:  {-1, 1},
+	DownRight: {1, 1},
+}
+
+// MoveInput is the movement input that steps by dir, None when dir isn't one step
+func MoveInput(dir Pos) InputType {
+	for typ, d := range moveDirections {
+		if d == dir {
+			return typ
+		}
+	}
+	return None
+}
+
+// path costs are in tenths of a step so a diagonal can cost about root two straight ones
+const (
+	straightCost = 10
+	diagonalCost = 14
+)
+
+// SetDiagonal turns diagonal movement on or off for the player and the monsters on every level
+func (game *Game) SetDiagonal(on bool) {
+	game.diagonal = on
+	for _, level := range game.Levels {
+		level.Diagonal = on
+	}
+}
+
+// the directions anyone on the level can step in
+func (level *Level) directions() []Pos {
+	if level.Diagonal {
+		return directions
+	}
+	return directions[:4]
+}
+
+func isDiagonal(from, to Pos) bool {
+	return from.X != to.X && from.Y != to.Y
+}
+
+// canStep is whether from and to touch and the step between them is allowed. Nobody
+// squeezes diagonally past the corner of a wall or a closed door, so a diagonal needs
+// both of the straight tiles beside it to be open. Monsters on them don't matter
+func (level *Level) canStep(from, to Pos) bool {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	if dx < -1 || dx > 1 || dy < -1 || dy > 1 || (dx == 0 && dy == 0) {
+		return false
+	}
+	if !isDiagonal(from, to) {
+		return true
+	}
+	return level.Diagonal && canPass(level, Pos{to.X, from.Y}) && canPass(level, Pos{from.X, to.Y})
+}
+
+// tileCost is how many steps' worth of effort it takes to walk onto pos
+// and a closed door is another step to open first
+func tileCost(level *Level, pos Pos) int {
+	tile := level.Map[pos.Y][pos.X]
+	cost := moveTurns(level, pos)
+	if tile.Rune == Lava {
+		cost = lavaAvoidance
+	}
+	if tile.OverlayRune == CloseDoor {
+		cost++
+	}
+	return cost
+}
+
+// the cost of stepping from one tile onto the next
+func stepCost(level *Level, from, to Pos) int {
+	if isDiagonal(from, to) {
+		return diagonalCost * tileCost(level, to)
+	}
+	return straightCost * tileCost(level, to)
+}
+
+// the cheapest a walk between two tiles could be if nothing was in the way,
+// octile distance with diagonals and manhattan without
+func (level *Level) estimate(from, to Pos) int {
+	dx, dy := abs(to.X-from.X), abs(to.Y-from.Y)
+	if !level.Diagonal {
+		return straightCost * (dx + dy)
+	}
+	if dx < dy {
+		dx, dy = dy, dx
+	}
+	return straightCost*(dx-dy) + diagonalCost*dy
+}
+
+// levelGraph is a level as the pathfinding package sees it, walk is which tiles are open
+type levelGraph struct {
+	level *Level
+	walk  func(*Level, Pos) bool
+}
+
+func (g levelGraph) Neighbors(pos Pos) []Pos {
+	return neighborsWhere(g.level, pos, g.walk)
+}
+
+func (g levelGraph) Cost(from, to Pos) int {
+	return stepCost(g.level, from, to)
+}
+
+func (g levelGraph) Heuristic(pos, goal Pos) int {
+	return g.level.estimate(pos, goal)
+}
+
+// reverseGraph is levelGraph searched from the goal back out, each step costs what
+// walking it the other way would
+type reverseGraph struct {
+	levelGraph
+}
+
+func (g reverseGraph) Cost(from, to Pos) int {
+	return stepCost(g.level, to, from)
+}
@@ -0,0 +1,283 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Remote windows are drawn somewhere the game's levels can't be shared, like across the
+// network. Each turn the game copies what a window draws into a Snapshot, which can be
+// read and sent on while the game carries on, and the other side builds a Level from it.
+// Snapshots after the first only need the tiles that changed, see Diff
+
+// NewRemoteWindow makes a window whose frames carry a Snapshot of their level
+func NewRemoteWindow(view View) *Window {
+	w := NewWindow(view)
+	w.remote = true
+	return w
+}
+
+// Snapshot is everything a window draws of a level. Tiles come in Spans, either every row
+// in full or only what changed since the snapshot before, the rest is always whole.
+// The player is in every snapshot, Frame.HasPlayer says whether they're on the level
+type Snapshot struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// Spans cover every tile, otherwise they're patched onto the last snapshot's level
+	Full       bool              `json:"full,omitempty"`
+	Spans      []Span            `json:"spans,omitempty"`
+	Light      int               `json:"light"`
+	Player     *savedCharacter   `json:"player"`
+	Monsters   []*savedCharacter `json:"monsters"`
+	Items      []*savedItem      `json:"items"`
+	Events     []string          `json:"events"`
+	EventPos   int               `json:"eventPos"`
+	TurnEvents []*savedEvent     `json:"turnEvents,omitempty"`
+}
+
+// Span is a run of tiles along row Y starting at X, each string has a character per tile.
+// Tiles and Overlays are written the way saves write them
+type Span struct {
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Tiles    string `json:"tiles"`
+	Overlays string `json:"overlays"`
+	// '0' never seen, '1' seen before and '2' in sight
+	Sight string `json:"sight"`
+	// what light sources add there, '0' to '8'
+	Light string `json:"light"`
+}
+
+type savedEvent struct {
+	Kind   GameEvent `json:"kind"`
+	Actor  string    `json:"actor,omitempty"`
+	Target string    `json:"target,omitempty"`
+	X      int       `json:"x"`
+	Y      int       `json:"y"`
+	Amount int       `json:"amount,omitempty"`
+}
+
+// NewSnapshot copies level in full. Like everything else that reads a level it has to
+// happen on the game's goroutine
+func NewSnapshot(level *Level) *Snapshot {
+	s := &Snapshot{Name: level.Name, Height: len(level.Map), Full: true, Light: level.Ambient, EventPos: level.EventPos}
+	if s.Height > 0 {
+		s.Width = len(level.Map[0])
+	}
+	for y, row := range level.Map {
+		var tiles, overlays, sight, light strings.Builder
+		for _, t := range row {
+			tiles.WriteString(runeToString(t.Rune))
+			overlays.WriteString(runeToString(t.OverlayRune))
+			switch {
+			case t.Visible:
+				sight.WriteByte('2')
+			case t.Seen:
+				sight.WriteByte('1')
+			default:
+				sight.WriteByte('0')
+			}
+			light.WriteByte(byte('0' + t.Light))
+		}
+		s.Spans = append(s.Spans, Span{0, y, tiles.String(), overlays.String(), sight.String(), light.String()})
+	}
+
+	p := level.Player
+	s.Player = saveCharacter(&p.Character, p.Pos, p.Rune)
+	for _, pos := range sortedPositions(level.Monsters) {
+		m := level.Monsters[pos]
+		s.Monsters = append(s.Monsters, saveCharacter(&m.Character, m.Pos, m.Rune))
+	}
+	for _, pos := range sortedPositions(level.Items) {
+		for _, item := range level.Items[pos] {
+			s.Items = append(s.Items, saveItem(item))
+		}
+	}
+	s.Events = append([]string(nil), level.Events...)
+	for _, e := range level.TurnEvents {
+		s.TurnEvents = append(s.TurnEvents, &savedEvent{e.Kind, e.Actor, e.Target, e.Pos.X, e.Pos.Y, e.Amount})
+	}
+	return s
+}
+
+// Diff is s with only the tiles that changed since prev, a span per row from the first
+// change to the last. Both have to be full, a different level or size gives s back whole
+func (s *Snapshot) Diff(prev *Snapshot) *Snapshot {
+	if prev == nil || prev.Name != s.Name || prev.Width != s.Width || prev.Height != s.Height || !prev.Full || !s.Full {
+		return s
+	}
+	diff := *s
+	diff.Full = false
+	diff.Spans = nil
+	for y, span := range s.Spans {
+		old := prev.Spans[y]
+		cur := [4][]rune{[]rune(span.Tiles), []rune(span.Overlays), []rune(span.Sight), []rune(span.Light)}
+		was := [4][]rune{[]rune(old.Tiles), []rune(old.Overlays), []rune(old.Sight), []rune(old.Light)}
+		first, last := -1, -1
+		for x := 0; x < s.Width; x++ {
+			for i := range cur {
+				if cur[i][x] != was[i][x] {
+					if first == -1 {
+						first = x
+					}
+					last = x
+					break
+				}
+			}
+		}
+		if first == -1 {
+			continue
+		}
+		cut := func(r []rune) string { return string(r[first : last+1]) }
+		diff.Spans = append(diff.Spans, Span{first, y, cut(cur[0]), cut(cur[1]), cut(cur[2]), cut(cur[3])})
+	}
+	return &diff
+}
+
+// Level builds the level s describes. A snapshot that isn't Full is patched onto prev,
+// the level built from the one before it, which is left alone so whoever is still
+// drawing it can carry on
+func (s *Snapshot) Level(prev *Level) (*Level, error) {
+	if s.Player == nil {
+		return nil, fmt.Errorf("snapshot of %s has no player", s.Name)
+	}
+	player := &Player{}
+	loadCharacter(s.Player, &player.Character)
+	level := NewLevel(s.Name, s.Width, s.Height, player)
+	if !s.Full {
+		if prev == nil || prev.Name != s.Name || len(prev.Map) != s.Height || (s.Height > 0 && len(prev.Map[0]) != s.Width) {
+			return nil, fmt.Errorf("snapshot of %s only has changes and there's nothing like it to apply them to", s.Name)
+		}
+		for y := range prev.Map {
+			copy(level.Map[y], prev.Map[y])
+		}
+	}
+	for _, span := range s.Spans {
+		tiles, overlays := []rune(span.Tiles), []rune(span.Overlays)
+		n := len(tiles)
+		if len(overlays) != n || len(span.Sight) != n || len(span.Light) != n {
+			return nil, fmt.Errorf("snapshot of %s: span at %d,%d has mismatched widths", s.Name, span.X, span.Y)
+		}
+		if span.Y < 0 || span.Y >= s.Height || span.X < 0 || span.X+n > s.Width {
+			return nil, fmt.Errorf("snapshot of %s: span at %d,%d is off the map", s.Name, span.X, span.Y)
+		}
+		row := level.Map[span.Y]
+		for i := 0; i < n; i++ {
+			light := int(span.Light[i] - '0')
+			if light < 0 || light > MaxLight {
+				return nil, fmt.Errorf("snapshot of %s: light at %d,%d isn't 0-%d", s.Name, span.X+i, span.Y, MaxLight)
+			}
+			row[span.X+i] = Tile{
+				Rune:        stringToRune(string(tiles[i])),
+				OverlayRune: stringToRune(string(overlays[i])),
+				Visible:     span.Sight[i] == '2',
+				Seen:        span.Sight[i] != '0',
+				Light:       light,
+			}
+		}
+	}
+
+	level.Ambient = s.Light
+	for _, sm := range s.Monsters {
+		m := loadMonster(sm)
+		level.Monsters[m.Pos] = m
+	}
+	for _, si := range s.Items {
+		item := loadItem(si)
+		level.Items[item.Pos] = append(level.Items[item.Pos], item)
+	}
+	if len(s.Events) > 0 {
+		level.Events = s.Events
+		level.EventPos = s.EventPos
+	}
+	for _, e := range s.TurnEvents {
+		level.TurnEvents = append(level.TurnEvents, Event{e.Kind, e.Actor, e.Target, Pos{e.X, e.Y}, e.Amount})
+	}
+	return level, nil
+}
+
+// ItemRef says where the player would find an item: In "bag" or "ground" under them
+// with its Index there, or "worn" with Index counting the slots from the helmet to the
+// second ring. Inputs that can't carry the *Item, like ones from across the network,
+// point at it with one
+type ItemRef struct {
+	In    string `json:"in"`
+	Index int    `json:"index"`
+}
+
+func (ref ItemRef) String() string {
+	return ref.In + " " + strconv.Itoa(ref.Index)
+}
+
+// where the item an input acts on has to be
+var itemInputs = map[InputType]string{
+	TakeItem:    "ground",
+	DropItem:    "bag",
+	EquipItem:   "bag",
+	UseItem:     "bag",
+	UnequipItem: "worn",
+}
+
+// TakesItem is where the item typ acts on has to be, "" for inputs that don't act on one
+func TakesItem(typ InputType) string {
+	return itemInputs[typ]
+}
+
+// resolveItem looks up the item an input points at with a Ref. False when the input
+// needs an item and hasn't got one, or its Ref points somewhere the input can't reach
+func (level *Level) resolveItem(input *Input) bool {
+	from := TakesItem(input.Typ)
+	if from == "" {
+		return true
+	}
+	if input.Ref != nil {
+		if input.Ref.In != from {
+			return false
+		}
+		input.Item = level.ItemAt(*input.Ref)
+	}
+	return input.Item != nil
+}
+
+// RefTo is where item is from the player's side, false when they can't reach it
+func (level *Level) RefTo(item *Item) (ItemRef, bool) {
+	p := level.Player
+	for i, it := range p.Items {
+		if it == item {
+			return ItemRef{"bag", i}, true
+		}
+	}
+	for i, slot := range p.slots() {
+		if *slot == item {
+			return ItemRef{"worn", i}, true
+		}
+	}
+	for i, it := range level.Items[p.Pos] {
+		if it == item {
+			return ItemRef{"ground", i}, true
+		}
+	}
+	return ItemRef{}, false
+}
+
+// ItemAt is the item ref points at, nil when there isn't one there
+func (level *Level) ItemAt(ref ItemRef) *Item {
+	p := level.Player
+	var items []*Item
+	switch ref.In {
+	case "bag":
+		items = p.Items
+	case "worn":
+		for _, slot := range p.slots() {
+			items = append(items, *slot)
+		}
+	case "ground":
+		items = level.Items[p.Pos]
+	}
+	if ref.Index < 0 || ref.Index >= len(items) {
+		return nil
+	}
+	return items[ref.Index]
+}
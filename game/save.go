@@ -0,0 +1,510 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Save files are JSON documents:
+//
+//	{
+//	  "version": 1,
+//	  "currentLevel": "level1",
+//	  "player": { character },
+//	  "levels": [
+//	    {
+//	      "name": "level1",
+//	      "tiles":    ["####", "#..#"],  one string per row, ' ' is Blank
+//	      "overlays": ["    ", " | u"],  doors, stairs and wall torches, ' ' is none
+//	      "seen":     ["0110", "0110"],  fog of war, '1' is a tile the player has seen
+//	      "monsters": [ { character } ],
+//	      "items":    [ { item } ],       items lying on the ground
+//	      "portals":  [ { "x": 43, "y": 2, "level": "level2", "toX": 3, "toY": 2 } ],
+//	      "events":   ["..."],            the whole ring buffer
+//	      "eventPos": 3,
+//	      "light": 8                      the level's Ambient
+//	    }
+//	  ]
+//	}
+//
+// A character is { "name", "rune", "x", "y", "hitpoints", "maxHitpoints", "strength", "speed",
+// "actionPoints", "sightRange", "accuracy", "evasion", "swims", "light", "items": [item], "helmet", "weapon", "armor", "boots", "shield",
+// "amulet", "ring1", "ring2" }, each slot holding an item or left out when empty. Monsters also have
+// the "glyph" they were placed with in the map and "ai": { "state", "lastSeenX", "lastSeenY", "searchLeft", "wander", "flee", "search", "doors" }.
+// An item is { "id", "type", "slot", "effect", "name", "rune", "x", "y", "power", "description",
+// "modifiers": { "attack", "defense", "speed", "sight", "hp", "accuracy", "evasion", "light" } }, modifiers are written for every
+// item with a slot.
+// Files written with any other version are refused, not converted.
+// The combat dice aren't saved, a loaded game rolls differently from the one that was saved.
+// Neither is who was still waiting to act in the current tick, a loaded game starts a fresh one.
+// Portals point at other levels by name, the player is shared by every level.
+// Visible and Light on tiles aren't saved, they're recomputed from the player's position on load.
+// Diagonal movement isn't saved either, it's a setting of the game doing the loading.
+const saveVersion = 1
+
+// where the ui save/load keys read and write
+const SaveFile = "rpg.sav"
+
+type savedItem struct {
+	ID          string      `json:"id,omitempty"`
+	Typ         ItemType    `json:"type"`
+	Slot        Slot        `json:"slot"`
+	Effect      Effect      `json:"effect,omitempty"`
+	Name        string      `json:"name"`
+	Rune        string      `json:"rune"`
+	X           int         `json:"x"`
+	Y           int         `json:"y"`
+	Power       float64     `json:"power"`
+	Description string      `json:"description,omitempty"`
+	Modifiers   *savedStats `json:"modifiers,omitempty"`
+}
+
+type savedStats struct {
+	Attack       int     `json:"attack,omitempty"`
+	Defense      int     `json:"defense,omitempty"`
+	Speed        float64 `json:"speed,omitempty"`
+	SightRange   int     `json:"sight,omitempty"`
+	MaxHitpoints int     `json:"hp,omitempty"`
+	Accuracy     int     `json:"accuracy,omitempty"`
+	Evasion      int     `json:"evasion,omitempty"`
+	Light        int     `json:"light,omitempty"`
+}
+
+type savedCharacter struct {
+	Name         string       `json:"name"`
+	Rune         string       `json:"rune"`
+	X            int          `json:"x"`
+	Y            int          `json:"y"`
+	Hitpoints    int          `json:"hitpoints"`
+	MaxHitpoints int          `json:"maxHitpoints"`
+	Strength     int          `json:"strength"`
+	Speed        float64      `json:"speed"`
+	ActionPoints float64      `json:"actionPoints"`
+	SightRange   int          `json:"sightRange"`
+	Accuracy     int          `json:"accuracy"`
+	Evasion      int          `json:"evasion"`
+	Swims        bool         `json:"swims"`
+	Light        int          `json:"light"`
+	Items        []*savedItem `json:"items"`
+	Helmet       *savedItem   `json:"helmet,omitempty"`
+	Weapon       *savedItem   `json:"weapon,omitempty"`
+	Armor        *savedItem   `json:"armor,omitempty"`
+	Boots        *savedItem   `json:"boots,omitempty"`
+	Shield       *savedItem   `json:"shield,omitempty"`
+	Amulet       *savedItem   `json:"amulet,omitempty"`
+	Ring1        *savedItem   `json:"ring1,omitempty"`
+	Ring2        *savedItem   `json:"ring2,omitempty"`
+	// monsters only
+	Glyph string   `json:"glyph,omitempty"`
+	AI    *savedAI `json:"ai,omitempty"`
+}
+
+type savedAI struct {
+	State      AIState `json:"state"`
+	LastSeenX  int     `json:"lastSeenX"`
+	LastSeenY  int     `json:"lastSeenY"`
+	SearchLeft int     `json:"searchLeft"`
+	Wander     int     `json:"wander"`
+	Flee       int     `json:"flee"`
+	Search     int     `json:"search"`
+	Doors      bool    `json:"doors"`
+}
+
+type savedPortal struct {
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Level string `json:"level"`
+	ToX   int    `json:"toX"`
+	ToY   int    `json:"toY"`
+}
+
+type savedLevel struct {
+	Name     string            `json:"name"`
+	Tiles    []string          `json:"tiles"`
+	Overlays []string          `json:"overlays"`
+	Seen     []string          `json:"seen"`
+	Monsters []*savedCharacter `json:"monsters"`
+	Items    []*savedItem      `json:"items"`
+	Portals  []*savedPortal    `json:"portals"`
+	Events   []string          `json:"events"`
+	EventPos int               `json:"eventPos"`
+	Light    int               `json:"light"`
+}
+
+type saveFile struct {
+	Version      int             `json:"version"`
+	CurrentLevel string          `json:"currentLevel"`
+	Player       *savedCharacter `json:"player"`
+	Levels       []*savedLevel   `json:"levels"`
+}
+
+func runeToString(r rune) string {
+	if r == Blank {
+		return " "
+	}
+	return string(r)
+}
+
+func stringToRune(s string) rune {
+	for _, r := range s {
+		if r == ' ' {
+			return Blank
+		}
+		return r
+	}
+	return Blank
+}
+
+func saveItem(item *Item) *savedItem {
+	if item == nil {
+		return nil
+	}
+	s := &savedItem{item.ID, item.Typ, item.Slot, item.Effect, item.Name, runeToString(item.Rune), item.X, item.Y, item.power, item.Description, nil}
+	if item.Slot != NoSlot {
+		mods := savedStats(item.Modifiers)
+		s.Modifiers = &mods
+	}
+	return s
+}
+
+func loadItem(s *savedItem) *Item {
+	if s == nil {
+		return nil
+	}
+	item := &Item{
+		Typ:         s.Typ,
+		Entity:      Entity{Pos{s.X, s.Y}, s.Name, stringToRune(s.Rune)},
+		power:       s.Power,
+		ID:          s.ID,
+		Slot:        s.Slot,
+		Effect:      s.Effect,
+		Description: s.Description,
+	}
+	if s.Modifiers != nil {
+		item.Modifiers = Stats(*s.Modifiers)
+	}
+	return item
+}
+
+func saveCharacter(c *Character, pos Pos, r rune) *savedCharacter {
+	s := &savedCharacter{
+		Name:         c.Name,
+		Rune:         runeToString(r),
+		X:            pos.X,
+		Y:            pos.Y,
+		Hitpoints:    c.Hitpoints,
+		MaxHitpoints: c.MaxHitpoints,
+		Strength:     c.Strength,
+		Speed:        c.Speed,
+		ActionPoints: c.ActionPoints,
+		SightRange:   c.SightRange,
+		Accuracy:     c.Accuracy,
+		Evasion:      c.Evasion,
+		Swims:        c.Swims,
+		Light:        c.Light,
+		Items:        make([]*savedItem, 0, len(c.Items)),
+		Helmet:       saveItem(c.Helmet),
+		Weapon:       saveItem(c.Weapon),
+		Armor:        saveItem(c.Armor),
+		Boots:        saveItem(c.Boots),
+		Shield:       saveItem(c.Shield),
+		Amulet:       saveItem(c.Amulet),
+		Ring1:        saveItem(c.Ring1),
+		Ring2:        saveItem(c.Ring2),
+	}
+	for _, item := range c.Items {
+		s.Items = append(s.Items, saveItem(item))
+	}
+	return s
+}
+
+func loadCharacter(s *savedCharacter, c *Character) {
+	c.Name = s.Name
+	c.Rune = stringToRune(s.Rune)
+	c.Pos = Pos{s.X, s.Y}
+	c.Hitpoints = s.Hitpoints
+	c.MaxHitpoints = s.MaxHitpoints
+	c.Strength = s.Strength
+	c.Speed = s.Speed
+	c.ActionPoints = s.ActionPoints
+	c.SightRange = s.SightRange
+	c.Accuracy = s.Accuracy
+	c.Evasion = s.Evasion
+	c.Swims = s.Swims
+	c.Light = s.Light
+	c.Items = make([]*Item, 0, len(s.Items))
+	for _, item := range s.Items {
+		c.Items = append(c.Items, loadItem(item))
+	}
+	c.Helmet = loadItem(s.Helmet)
+	c.Weapon = loadItem(s.Weapon)
+	c.Armor = loadItem(s.Armor)
+	c.Boots = loadItem(s.Boots)
+	c.Shield = loadItem(s.Shield)
+	c.Amulet = loadItem(s.Amulet)
+	c.Ring1 = loadItem(s.Ring1)
+	c.Ring2 = loadItem(s.Ring2)
+}
+
+// loadMonster is a monster from a save or a snapshot, snapshots leave out the ai since
+// remote windows only draw monsters, those start idle
+func loadMonster(sm *savedCharacter) *Monster {
+	m := &Monster{}
+	loadCharacter(sm, &m.Character)
+	m.Pos = m.Character.Pos
+	m.Rune = m.Character.Rune
+	m.Glyph = stringToRune(sm.Glyph)
+	m.Behavior = defaultBehavior
+	if ai := sm.AI; ai != nil {
+		m.State = ai.State
+		m.LastSeen = Pos{ai.LastSeenX, ai.LastSeenY}
+		m.searchLeft = ai.SearchLeft
+		m.Behavior = Behavior{ai.Wander, ai.Flee, ai.Search, ai.Doors}
+	}
+	return m
+}
+
+func saveLevel(level *Level) *savedLevel {
+	s := &savedLevel{Name: level.Name, Events: level.Events, EventPos: level.EventPos, Light: level.Ambient}
+	for _, row := range level.Map {
+		var tiles, overlays, seen strings.Builder
+		for _, t := range row {
+			tiles.WriteString(runeToString(t.Rune))
+			overlays.WriteString(runeToString(t.OverlayRune))
+			if t.Seen {
+				seen.WriteByte('1')
+			} else {
+				seen.WriteByte('0')
+			}
+		}
+		s.Tiles = append(s.Tiles, tiles.String())
+		s.Overlays = append(s.Overlays, overlays.String())
+		s.Seen = append(s.Seen, seen.String())
+	}
+
+	// maps iterate in random order, sort by position so the same game always saves the same bytes
+	for _, pos := range sortedPositions(level.Monsters) {
+		m := level.Monsters[pos]
+		sm := saveCharacter(&m.Character, m.Pos, m.Rune)
+		sm.Glyph = runeToString(m.Glyph)
+		sm.AI = &savedAI{m.State, m.LastSeen.X, m.LastSeen.Y, m.searchLeft, m.Behavior.WanderChance, m.Behavior.FleeAt, m.Behavior.SearchTurns, m.Behavior.OpensDoors}
+		s.Monsters = append(s.Monsters, sm)
+	}
+	for _, pos := range sortedPositions(level.Items) {
+		for _, item := range level.Items[pos] {
+			s.Items = append(s.Items, saveItem(item))
+		}
+	}
+	for _, pos := range sortedPositions(level.Portals) {
+		lp := level.Portals[pos]
+		s.Portals = append(s.Portals, &savedPortal{pos.X, pos.Y, lp.Level.Name, lp.X, lp.Y})
+	}
+	return s
+}
+
+func sortedPositions[V any](m map[Pos]V) []Pos {
+	positions := make([]Pos, 0, len(m))
+	for pos := range m {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Y != positions[j].Y {
+			return positions[i].Y < positions[j].Y
+		}
+		return positions[i].X < positions[j].X
+	})
+	return positions
+}
+
+// Save writes every level, the player and the event logs to w
+func (game *Game) Save(w io.Writer) error {
+	save := &saveFile{
+		Version:      saveVersion,
+		CurrentLevel: game.CurrentLevel.Name,
+		Player:       saveCharacter(&game.CurrentLevel.Player.Character, game.CurrentLevel.Player.Pos, game.CurrentLevel.Player.Rune),
+	}
+	names := make([]string, 0, len(game.Levels))
+	for name := range game.Levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		save.Levels = append(save.Levels, saveLevel(game.Levels[name]))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(save)
+}
+
+// Load reads a game written by Save. The returned game has an input channel
+// but no windows, they're attached by the caller
+func Load(r io.Reader) (*Game, error) {
+	var save saveFile
+	err := json.NewDecoder(r).Decode(&save)
+	if err != nil {
+		return nil, err
+	}
+	if save.Version != saveVersion {
+		return nil, fmt.Errorf("unsupported save version %d, expected %d", save.Version, saveVersion)
+	}
+	if save.Player == nil {
+		return nil, fmt.Errorf("save file has no player")
+	}
+
+	player := &Player{}
+	loadCharacter(save.Player, &player.Character)
+
+	levels := make(map[string]*Level)
+	for _, s := range save.Levels {
+		if s == nil {
+			return nil, fmt.Errorf("save file has an empty level")
+		}
+		level := NewLevel(s.Name, 0, 0, player)
+		level.Ambient = s.Light
+		if len(s.Events) > 0 {
+			level.Events = s.Events
+			level.EventPos = s.EventPos
+		}
+
+		if len(s.Overlays) != len(s.Tiles) || len(s.Seen) != len(s.Tiles) {
+			return nil, fmt.Errorf("level %s: tiles, overlays and seen have different heights", s.Name)
+		}
+		level.Map = make([][]Tile, len(s.Tiles))
+		for y := range s.Tiles {
+			tiles := []rune(s.Tiles[y])
+			overlays := []rune(s.Overlays[y])
+			seen := s.Seen[y]
+			if len(overlays) != len(tiles) || len(seen) != len(tiles) {
+				return nil, fmt.Errorf("level %s: row %d has mismatched widths", s.Name, y)
+			}
+			level.Map[y] = make([]Tile, len(tiles))
+			for x := range tiles {
+				level.Map[y][x] = Tile{
+					Rune:        stringToRune(string(tiles[x])),
+					OverlayRune: stringToRune(string(overlays[x])),
+					Seen:        seen[x] == '1',
+				}
+			}
+		}
+
+		for _, sm := range s.Monsters {
+			if sm == nil {
+				return nil, fmt.Errorf("level %s: empty monster", s.Name)
+			}
+			m := loadMonster(sm)
+			level.Monsters[m.Pos] = m
+		}
+		for _, si := range s.Items {
+			if si == nil {
+				return nil, fmt.Errorf("level %s: empty item", s.Name)
+			}
+			item := loadItem(si)
+			level.Items[item.Pos] = append(level.Items[item.Pos], item)
+		}
+		err = checkLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		levels[s.Name] = level
+	}
+
+	// portals are resolved once every level exists
+	for _, s := range save.Levels {
+		level := levels[s.Name]
+		for _, sp := range s.Portals {
+			to := levels[sp.Level]
+			if to == nil {
+				return nil, fmt.Errorf("level %s: portal at %d,%d leads to unknown level %s", s.Name, sp.X, sp.Y, sp.Level)
+			}
+			if !inRange(level, Pos{sp.X, sp.Y}) || !inRange(to, Pos{sp.ToX, sp.ToY}) {
+				return nil, fmt.Errorf("level %s: portal at %d,%d to %d,%d on %s is off the map", s.Name, sp.X, sp.Y, sp.ToX, sp.ToY, sp.Level)
+			}
+			level.Portals[Pos{sp.X, sp.Y}] = &LevelPos{to, Pos{sp.ToX, sp.ToY}}
+		}
+	}
+
+	current := levels[save.CurrentLevel]
+	if current == nil {
+		return nil, fmt.Errorf("save file current level %s doesn't exist", save.CurrentLevel)
+	}
+	if !inRange(current, player.Pos) {
+		return nil, fmt.Errorf("level %s: player at %d,%d is off the map", current.Name, player.X, player.Y)
+	}
+
+	game := &Game{items: defaultItemCatalog}
+	game.InputChan = make(chan *Input)
+	game.Levels = levels
+	game.CurrentLevel = current
+	game.CurrentLevel.refreshSight()
+	return game, nil
+}
+
+// checkLevel is an error for anything in a loaded level that would be read past the end
+// of the map or the event log: rows of different widths, an event position outside the
+// log, light out of range and monsters or items off the map
+func checkLevel(level *Level) error {
+	if len(level.Map) == 0 || len(level.Map[0]) == 0 {
+		return fmt.Errorf("level %s has no tiles", level.Name)
+	}
+	for y, row := range level.Map {
+		if len(row) != len(level.Map[0]) {
+			return fmt.Errorf("level %s: row %d is %d tiles wide, the first is %d", level.Name, y, len(row), len(level.Map[0]))
+		}
+	}
+	if level.EventPos < 0 || level.EventPos >= len(level.Events) {
+		return fmt.Errorf("level %s: event position %d isn't in the %d events", level.Name, level.EventPos, len(level.Events))
+	}
+	if level.Ambient < 0 || level.Ambient > MaxLight {
+		return fmt.Errorf("level %s: light %d isn't 0-%d", level.Name, level.Ambient, MaxLight)
+	}
+	for _, pos := range sortedPositions(level.Monsters) {
+		if !inRange(level, pos) {
+			return fmt.Errorf("level %s: %s at %d,%d is off the map", level.Name, level.Monsters[pos].Name, pos.X, pos.Y)
+		}
+	}
+	for _, pos := range sortedPositions(level.Items) {
+		if !inRange(level, pos) {
+			return fmt.Errorf("level %s: %s at %d,%d is off the map", level.Name, level.Items[pos][0].Name, pos.X, pos.Y)
+		}
+	}
+	return nil
+}
+
+func (game *Game) saveToFile() {
+	level := game.CurrentLevel
+	file, err := os.Create(SaveFile)
+	if err != nil {
+		level.message("Couldn't save: " + err.Error())
+		return
+	}
+	defer file.Close()
+	err = game.Save(file)
+	if err != nil {
+		level.message("Couldn't save: " + err.Error())
+		return
+	}
+	level.message("Game saved")
+}
+
+func (game *Game) loadFromFile() {
+	file, err := os.Open(SaveFile)
+	if err != nil {
+		game.CurrentLevel.message("Couldn't load: " + err.Error())
+		return
+	}
+	defer file.Close()
+	loaded, err := Load(file)
+	if err != nil {
+		game.CurrentLevel.message("Couldn't load: " + err.Error())
+		return
+	}
+	game.Levels = loaded.Levels
+	game.CurrentLevel = loaded.CurrentLevel
+	game.SetDiagonal(game.diagonal)
+	game.CurrentLevel.message("Game loaded")
+}
@@ -0,0 +1,126 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// saveAndLoad is g after a trip through a save file
+func saveAndLoad(t *testing.T, g *Game) *Game {
+	t.Helper()
+	var buf bytes.Buffer
+	err := g.Save(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
+
+// Monsters remember the glyph they were placed with, which needn't be the sprite they're
+// drawn with
+func TestSaveMonsterGlyph(t *testing.T) {
+	maps := mapDir("room", map[string]string{
+		"room.map":     "####\n#@Z#\n####\n",
+		"monsters.txt": "Z, Zombie, 30, 4, 0.5, 6, S, wander=0, doors=true\n",
+	})
+	g, err := NewGame(0, maps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := saveAndLoad(t, g).CurrentLevel.Monsters[Pos{2, 1}]
+	if m == nil {
+		t.Fatal("the zombie wasn't loaded")
+	}
+	if m.Glyph != 'Z' || m.Rune != 'S' {
+		t.Errorf("glyph %q and sprite %q, want 'Z' and 'S'", m.Glyph, m.Rune)
+	}
+	if m.Behavior.WanderChance != 0 || !m.Behavior.OpensDoors {
+		t.Errorf("behavior %+v, want the zombie's own", m.Behavior)
+	}
+}
+
+// A loaded game saves the same bytes it was loaded from
+func TestSaveRoundTrip(t *testing.T) {
+	g, err := NewGame(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first, second bytes.Buffer
+	err = g.Save(&first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = saveAndLoad(t, g).Save(&second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("saving a loaded game wrote something different")
+	}
+}
+
+// Broken save files are errors, not panics
+func TestLoadBadFiles(t *testing.T) {
+	g, err := NewGame(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var good bytes.Buffer
+	err = g.Save(&good)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		spoil func(*saveFile)
+		// part of the error message
+		want string
+	}{
+		{"old version", func(s *saveFile) { s.Version = 9 }, "unsupported save version 9"},
+		{"no player", func(s *saveFile) { s.Player = nil }, "no player"},
+		{"empty level", func(s *saveFile) { s.Levels[0] = nil }, "empty level"},
+		{"no tiles", func(s *saveFile) {
+			s.Levels[0].Tiles, s.Levels[0].Overlays, s.Levels[0].Seen = nil, nil, nil
+		}, "level1 has no tiles"},
+		{"short row", func(s *saveFile) {
+			l := s.Levels[0]
+			l.Tiles[3], l.Overlays[3], l.Seen[3] = l.Tiles[3][:5], l.Overlays[3][:5], l.Seen[3][:5]
+		}, "row 3 is 5 tiles wide"},
+		{"mismatched row", func(s *saveFile) { s.Levels[0].Seen[2] = "0" }, "row 2 has mismatched widths"},
+		{"event position past the end", func(s *saveFile) { s.Levels[0].EventPos = len(s.Levels[0].Events) }, "event position"},
+		{"negative event position", func(s *saveFile) { s.Levels[0].EventPos = -1 }, "event position -1"},
+		{"too much light", func(s *saveFile) { s.Levels[1].Light = MaxLight + 1 }, "level2: light"},
+		{"empty monster", func(s *saveFile) { s.Levels[0].Monsters[0] = nil }, "empty monster"},
+		{"monster off the map", func(s *saveFile) { s.Levels[0].Monsters[0].X = 1000 }, "is off the map"},
+		{"empty item", func(s *saveFile) { s.Levels[0].Items[0] = nil }, "empty item"},
+		{"item off the map", func(s *saveFile) { s.Levels[0].Items[0].Y = -1 }, "is off the map"},
+		{"portal off the map", func(s *saveFile) { s.Levels[0].Portals[0].X = -5 }, "portal at -5,"},
+		{"portal to off the map", func(s *saveFile) { s.Levels[0].Portals[0].ToY = 1000 }, "is off the map"},
+		{"portal to nowhere", func(s *saveFile) { s.Levels[0].Portals[0].Level = "cellar" }, "unknown level cellar"},
+		{"player off the map", func(s *saveFile) { s.Player.X = 1000 }, "player at 1000,"},
+		{"unknown current level", func(s *saveFile) { s.CurrentLevel = "cellar" }, "cellar doesn't exist"},
+	}
+	for _, tt := range tests {
+		var save saveFile
+		err := json.Unmarshal(good.Bytes(), &save)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tt.spoil(&save)
+		data, err := json.Marshal(&save)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Load(bytes.NewReader(data))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error with %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package game
+
+import (
+	"math"
+
+	"github.com/gorillana/rpg/pathfinding"
+)
+
+// Turns are handed out by energy. Every tick of the clock each actor on the level gains
+// its Speed in ActionPoints, and anyone with a whole point can act, paying for the action
+// out of them. Whoever has the most energy goes first, so a player twice as fast as a
+// monster gets two actions for its one. Ties go to whoever was queued first, the player
+// and then the monsters top to bottom, left to right, so the same game always plays out
+// the same way.
+
+// the energy an action costs unless it says otherwise
+const actionCost = 1
+
+// queues an actor for the rest of this tick, m is nil for the player
+func (level *Level) queueActor(m *Monster) {
+	c := &level.Player.Character
+	if m != nil {
+		c = &m.Character
+	}
+	// the queue pops the lowest first and energy is a float, so thousandths of a point, negated
+	level.turnQueue.Push(m, -int(math.Round(c.ActionPoints*1000)))
+}
+
+// everyone gains a tick's worth of energy and goes back in the queue
+func (level *Level) tick() {
+	level.turnQueue = pathfinding.NewQueue[*Monster]()
+	level.Player.ActionPoints += level.Player.Stats().Speed
+	level.queueActor(nil)
+	for _, pos := range sortedPositions(level.Monsters) {
+		m := level.Monsters[pos]
+		m.ActionPoints += m.Stats().Speed
+		level.queueActor(m)
+	}
+}
+
+// nextActor is whoever acts next, nil when it's the player's turn
+func (level *Level) nextActor() *Monster {
+	for {
+		for level.turnQueue != nil && level.turnQueue.Len() > 0 {
+			m, _ := level.turnQueue.Pop()
+			if m == nil {
+				if level.Player.ActionPoints >= actionCost {
+					return nil
+				}
+				continue
+			}
+			// killed since it was queued
+			if level.Monsters[m.Pos] != m {
+				continue
+			}
+			if m.ActionPoints >= actionCost {
+				return m
+			}
+		}
+		level.tick()
+	}
+}
+
+// playerActed charges the player for their last input and lets everyone else act until
+// it's the player's turn again
+func (game *Game) playerActed() {
+	level := game.CurrentLevel
+	level.Player.ActionPoints -= float64(game.turns)
+	if level.Player.ActionPoints >= actionCost {
+		level.queueActor(nil)
+	}
+
+	for level.Player.Hitpoints > 0 {
+		m := level.nextActor()
+		if m == nil {
+			return
+		}
+		m.Update(level)
+		if level.Monsters[m.Pos] == m && m.ActionPoints >= actionCost {
+			level.queueActor(m)
+		}
+	}
+}
+
+// endTurn is what follows an input that used up the player's turn
+func (game *Game) endTurn() {
+	level := game.CurrentLevel
+	// stepping into lava already burned the player in Move, staying in it burns too
+	if !level.Happened(Move) && !level.Happened(Portal) {
+		level.burn(&level.Player.Character, level.Player.Pos)
+	}
+	game.playerActed()
+	// monsters moving their glow about changes what's lit
+	game.CurrentLevel.refreshSight()
+	if game.CurrentLevel.Player.Hitpoints <= 0 {
+		game.playerDied()
+	}
+}
+
+// whether an input uses up the player's turn, saving and the like are free
+func takesTurn(typ InputType) bool {
+	switch typ {
+	case Up, Down, Left, Right, UpLeft, UpRight, DownLeft, DownRight,
+		TakeAll, ShutDoor, TakeItem, DropItem, EquipItem, UnequipItem, UseItem:
+		return true
+	}
+	return false
+}
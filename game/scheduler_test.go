@@ -0,0 +1,154 @@
+package game
+
+import "testing"
+
+// an open room with walls around it, the player in the top left corner
+func testRoom(width, height int) *Level {
+	level := NewLevel("room", width, height, NewPlayer())
+	for y := range level.Map {
+		for x := range level.Map[y] {
+			level.Map[y][x].Rune = DirtFloor
+			if x == 0 || y == 0 || x == width-1 || y == height-1 {
+				level.Map[y][x].Rune = StoneWall
+			}
+		}
+	}
+	level.Player.Pos = Pos{1, 1}
+	level.Seed(1)
+	return level
+}
+
+// a monster at p that acts at speed and never moves
+func testMonster(level *Level, p Pos, speed float64) *Monster {
+	def := &MonsterDef{Name: "Statue", Hitpoints: 10, Strength: 1, Speed: speed, Accuracy: defaultAccuracy, Evasion: defaultEvasion}
+	m := def.spawn(p, nil)
+	level.Monsters[p] = m
+	return m
+}
+
+// takes turns the way playerActed does, a point each, until the player has had turns of
+// them, and is how many each monster had before the player's next
+func countActions(level *Level, turns int) map[*Monster]int {
+	acted := make(map[*Monster]int)
+	for {
+		m := level.nextActor()
+		c := &level.Player.Character
+		if m == nil {
+			if turns == 0 {
+				return acted
+			}
+			turns--
+		} else {
+			c = &m.Character
+			acted[m]++
+		}
+		c.ActionPoints -= actionCost
+		if c.ActionPoints >= actionCost {
+			level.queueActor(m)
+		}
+	}
+}
+
+func TestSpeedRatios(t *testing.T) {
+	tests := []struct {
+		player, monster float64
+		turns, want     int
+	}{
+		{1, 1, 10, 10},
+		{1, 2, 10, 20},
+		{1, 0.5, 10, 5},
+		{1, 0.8, 10, 8},
+		{2, 1, 10, 5},
+		{0.5, 1, 10, 20},
+		{2, 3, 10, 15},
+	}
+	for _, tt := range tests {
+		level := testRoom(10, 10)
+		level.Player.Speed = tt.player
+		m := testMonster(level, Pos{8, 8}, tt.monster)
+		// a monster with more energy left than the player goes ahead of their next turn,
+		// so it can be an action over at the end
+		got := countActions(level, tt.turns)[m]
+		if got < tt.want || got > tt.want+1 {
+			t.Errorf("player speed %v, monster speed %v: %d actions in %d turns, want %d", tt.player, tt.monster, got, tt.turns, tt.want)
+		}
+	}
+}
+
+// Ties go to the player and then the monsters top to bottom, left to right
+func TestTieOrder(t *testing.T) {
+	level := testRoom(10, 10)
+	ms := []*Monster{
+		testMonster(level, Pos{5, 8}, 1),
+		testMonster(level, Pos{2, 3}, 1),
+		testMonster(level, Pos{7, 3}, 1),
+	}
+	want := []*Monster{nil, ms[1], ms[2], ms[0], nil, ms[1], ms[2], ms[0]}
+	for i, w := range want {
+		m := level.nextActor()
+		if m != w {
+			t.Fatalf("action %d: got %v, want %v", i, m, w)
+		}
+		if m == nil {
+			level.Player.ActionPoints -= actionCost
+		} else {
+			m.ActionPoints -= actionCost
+		}
+	}
+}
+
+// Attacks are a turn wherever the defender stands, only stepping into water takes two
+func TestAttackCost(t *testing.T) {
+	// a monster attacking the player as they wade
+	level := testRoom(10, 10)
+	level.Map[1][1].Rune = Water
+	m := testMonster(level, Pos{2, 1}, 1)
+	m.SightRange = 5
+	// only swimmers go after someone in water
+	m.Swims = true
+	m.ActionPoints = actionCost
+	m.Update(level)
+	if !level.Happened(Hit) && !level.Happened(Miss) && !level.Happened(Crit) {
+		t.Fatal("the monster didn't attack")
+	}
+	if m.ActionPoints != 0 {
+		t.Errorf("monster attack left %v action points, want 0", m.ActionPoints)
+	}
+
+	// the player attacking a monster in water, a step and a step into water, timed by how
+	// far a monster chasing them from across the room gets meanwhile
+	tests := []struct {
+		name  string
+		water bool
+		enemy bool
+		want  int
+	}{
+		{"attack", false, true, 1},
+		{"attack into water", true, true, 1},
+		{"step", false, false, 1},
+		{"step into water", true, false, 2},
+	}
+	for _, tt := range tests {
+		level := testRoom(20, 10)
+		g := NewGameFromLevel(0, func() *Level { return level })
+		if tt.water {
+			level.Map[1][2].Rune = Water
+		}
+		if tt.enemy {
+			testMonster(level, Pos{2, 1}, 1).Hitpoints = 1000
+		}
+		start := Pos{18, 8}
+		chaser := testMonster(level, start, 1)
+		chaser.SightRange = 100
+		// both ready to go, the player first as ties do
+		level.Player.ActionPoints = actionCost
+		chaser.ActionPoints = actionCost
+
+		g.handleInput(&Input{Typ: Right})
+		g.playerActed()
+		got := abs(chaser.Pos.X-start.X) + abs(chaser.Pos.Y-start.Y)
+		if got != tt.want {
+			t.Errorf("%s: the chaser took %d steps, want %d", tt.name, got, tt.want)
+		}
+	}
+}
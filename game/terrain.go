@@ -0,0 +1,94 @@
+package game
+
+// terrain besides StoneWall and DirtFloor, the runes are also what .map files use
+const (
+	// slow going, and only swimmers can get in at all
+	Water rune = '~'
+	// burns whoever steps in or stays there
+	Lava rune = '='
+	// can be walked through but not seen through
+	DeepGrass rune = '"'
+	// whoever steps on it slides the same way until something stops them
+	Ice rune = '_'
+)
+
+// what a turn in lava costs
+const lavaDamage = 5
+
+// how many plain steps path finding thinks a step into lava is worth, so monsters only
+// go through it when there's no other way
+const lavaAvoidance = 20
+
+// how many turns it takes to step onto pos, water takes two
+func moveTurns(level *Level, pos Pos) int {
+	if level.Map[pos.Y][pos.X].Rune == Water {
+		return 2
+	}
+	return 1
+}
+
+// walkable is canWalk for c, only swimmers get into water
+func (c *Character) walkable(level *Level, pos Pos) bool {
+	return canWalk(level, pos) && (c.Swims || level.Map[pos.Y][pos.X].Rune != Water)
+}
+
+// mover is what path finding needs to know about whoever's walking, which tiles they
+// can get onto besides the ones anyone can
+type mover struct {
+	swims      bool
+	opensDoors bool
+}
+
+// passes is canPass for the mover, monsters in the way aren't its concern
+func (mv mover) passes(level *Level, pos Pos) bool {
+	if !inRange(level, pos) {
+		return false
+	}
+	tile := level.Map[pos.Y][pos.X]
+	if tile.Rune == Water && !mv.swims {
+		return false
+	}
+	if tile.OverlayRune == CloseDoor && mv.opensDoors {
+		return tile.Rune != StoneWall && tile.Rune != Blank
+	}
+	return canPass(level, pos)
+}
+
+// walks is passes with nobody standing there
+func (mv mover) walks(level *Level, pos Pos) bool {
+	_, taken := level.Monsters[pos]
+	return !taken && mv.passes(level, pos)
+}
+
+// slide is where someone stepping from from onto to ends up. On ice they keep going
+// the same way until walk, a wall corner or the player stops them, whatever they end
+// up on is what they step onto
+func (level *Level) slide(from, to Pos, walk func(*Level, Pos) bool) Pos {
+	dir := Pos{to.X - from.X, to.Y - from.Y}
+	for level.Map[to.Y][to.X].Rune == Ice {
+		next := Pos{to.X + dir.X, to.Y + dir.Y}
+		if next == level.Player.Pos || !walk(level, next) || !level.canStep(to, next) {
+			break
+		}
+		to = next
+	}
+	return to
+}
+
+// burn hurts c if it's standing in lava, reporting whether it did
+func (level *Level) burn(c *Character, pos Pos) bool {
+	if level.Map[pos.Y][pos.X].Rune != Lava {
+		return false
+	}
+	c.Hitpoints -= lavaDamage
+	level.emit(Event{Kind: Burned, Actor: c.Name, Pos: pos, Amount: lavaDamage})
+	return true
+}
+
+// burns a monster standing in lava and clears it away if that finished it off
+func (level *Level) burnMonster(m *Monster) {
+	if level.burn(&m.Character, m.Pos) && m.Hitpoints <= 0 {
+		m.Kill(level)
+		level.emit(Event{Kind: Kill, Actor: "The lava", Target: m.Name, Pos: m.Pos})
+	}
+}
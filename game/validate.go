@@ -0,0 +1,338 @@
+package game
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorillana/rpg/pathfinding"
+)
+
+// Problem is something wrong with a map file or world.txt found by CheckMaps.
+// Line and Col start at 1, a zero Line means the problem is with the whole file
+type Problem struct {
+	File    string
+	Line    int
+	Col     int
+	Warning bool
+	Msg     string
+}
+
+func (p Problem) String() string {
+	kind := "error"
+	if p.Warning {
+		kind = "warning"
+	}
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", p.File, kind, p.Msg)
+	}
+	if p.Col == 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, kind, p.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", p.File, p.Line, p.Col, kind, p.Msg)
+}
+
+func posProblem(file string, pos Pos, warning bool, format string, args ...interface{}) Problem {
+	return Problem{file, pos.Y + 1, pos.X + 1, warning, fmt.Sprintf(format, args...)}
+}
+
+// splits a loader error back into one problem per bad glyph
+func loadProblems(file string, err error) []Problem {
+	errs := []error{err}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if ok {
+		errs = joined.Unwrap()
+	}
+	problems := make([]Problem, 0, len(errs))
+	for _, e := range errs {
+		var glyph *ErrUnknownGlyph
+		var level *ErrUnknownLevel
+		if errors.As(e, &glyph) {
+			problems = append(problems, Problem{glyph.File, glyph.Line, glyph.Col, false, fmt.Sprintf("unknown map character %q", glyph.Rune)})
+		} else if errors.As(e, &level) {
+			problems = append(problems, Problem{"world.txt", level.WorldLine, 0, false, fmt.Sprintf("no level named %q", level.Name)})
+		} else {
+			problems = append(problems, Problem{File: file, Msg: e.Error()})
+		}
+	}
+	return problems
+}
+
+func isFloor(level *Level, pos Pos) bool {
+	if !inRange(level, pos) {
+		return false
+	}
+	switch level.Map[pos.Y][pos.X].Rune {
+	case StoneWall, Blank:
+		return false
+	}
+	return true
+}
+
+// floorGraph is every floor tile a straight step from the next, doors and monsters
+// don't block it and every step costs the same
+type floorGraph struct {
+	level *Level
+}
+
+func (g floorGraph) Neighbors(pos Pos) []Pos {
+	neighbors := make([]Pos, 0, 4)
+	for _, next := range []Pos{{pos.X + 1, pos.Y}, {pos.X - 1, pos.Y}, {pos.X, pos.Y - 1}, {pos.X, pos.Y + 1}} {
+		if isFloor(g.level, next) {
+			neighbors = append(neighbors, next)
+		}
+	}
+	return neighbors
+}
+
+func (g floorGraph) Cost(from, to Pos) int {
+	return 1
+}
+
+func (g floorGraph) Heuristic(pos, goal Pos) int {
+	return abs(goal.X-pos.X) + abs(goal.Y-pos.Y)
+}
+
+// FloorGraph is level's floor for searches that only care about its layout, like the
+// dungeon generator's
+func FloorGraph(level *Level) pathfinding.Graph[Pos] {
+	return floorGraph{level}
+}
+
+// breadth-first flood through every floor tile, doors and monsters don't block it
+func (level *Level) reachable(starts []Pos) map[Pos]bool {
+	floor := make([]Pos, 0, len(starts))
+	for _, start := range starts {
+		if isFloor(level, start) {
+			floor = append(floor, start)
+		}
+	}
+	visited := make(map[Pos]bool)
+	pathfinding.BFS[Pos](floorGraph{level}, floor, func(pos Pos) bool {
+		visited[pos] = true
+		return false
+	})
+	return visited
+}
+
+// CheckMaps loads every .map file and world.txt from maps and reports anything
+// that would break or strand the player. It keeps going after errors so a
+// designer sees everything wrong in one run
+func CheckMaps(maps fs.FS) []Problem {
+	var problems []Problem
+
+	filenames, err := fs.Glob(maps, "*.map")
+	if err != nil {
+		return []Problem{{File: ".", Msg: err.Error()}}
+	}
+	if len(filenames) == 0 {
+		return []Problem{{File: ".", Msg: "no .map files found"}}
+	}
+
+	monsters, err := LoadMonsterDefs(maps)
+	if err != nil {
+		problems = append(problems, Problem{File: monstersFile, Msg: err.Error()})
+		monsters = defaultMonsterDefs
+	}
+	items, err := LoadItemCatalog(maps)
+	if err != nil {
+		problems = append(problems, Problem{File: itemsFile, Msg: err.Error()})
+		items = defaultItemCatalog
+	}
+	err = monsters.check(items)
+	if err != nil {
+		problems = append(problems, Problem{File: monstersFile, Msg: err.Error()})
+	}
+
+	player := NewPlayer()
+	levels := make(map[string]*Level)
+	files := make(map[*Level]string)
+	type playerStart struct {
+		file string
+		pos  Pos
+	}
+	var playerStarts []playerStart
+
+	for _, filename := range filenames {
+		// the loader only remembers the last '@' so look at the raw text
+		data, err := fs.ReadFile(maps, filename)
+		if err != nil {
+			problems = append(problems, Problem{File: filename, Msg: err.Error()})
+			continue
+		}
+		for y, line := range strings.Split(string(data), "\n") {
+			// columns count characters the way loadLevel lays them out, not bytes
+			for x, c := range []rune(line) {
+				if c == '@' {
+					playerStarts = append(playerStarts, playerStart{filename, Pos{x, y}})
+				}
+			}
+		}
+
+		level, err := loadLevel(maps, filename, player, monsters, items)
+		if err != nil {
+			problems = append(problems, loadProblems(filename, err)...)
+			continue
+		}
+		levels[level.Name] = level
+		files[level] = filename
+	}
+
+	if len(playerStarts) == 0 {
+		problems = append(problems, Problem{File: ".", Msg: "no map has a player start '@'"})
+	}
+	if len(playerStarts) > 1 {
+		for _, start := range playerStarts {
+			problems = append(problems, posProblem(start.file, start.pos, false, "player start '@' appears in more than one place"))
+		}
+	}
+
+	// where the player can arrive on each level, reachability is flooded from these
+	entrances := make(map[*Level][]Pos)
+	if len(playerStarts) == 1 {
+		for level, file := range files {
+			if file == playerStarts[0].file {
+				entrances[level] = append(entrances[level], playerStarts[0].pos)
+			}
+		}
+	}
+
+	game := &Game{Levels: levels, maps: maps}
+	err = game.loadWorldFile()
+	if err != nil {
+		problems = append(problems, loadProblems("world.txt", err)...)
+	}
+
+	names := make([]string, 0, len(levels))
+	for name := range levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		level := levels[name]
+		file := files[level]
+
+		for y, row := range level.Map {
+			for x := range row {
+				pos := Pos{x, y}
+				if !isFloor(level, pos) {
+					continue
+				}
+				for _, next := range []Pos{{x + 1, y}, {x - 1, y}, {x, y - 1}, {x, y + 1}} {
+					if !inRange(level, next) || level.Map[next.Y][next.X].Rune == Blank {
+						problems = append(problems, posProblem(file, pos, false, "floor opens onto empty space, is a wall missing?"))
+						break
+					}
+				}
+			}
+		}
+
+		for _, pos := range sortedPositions(level.Portals) {
+			to := level.Portals[pos]
+			if !canWalk(level, pos) {
+				problems = append(problems, posProblem(file, pos, false, "portal to %s isn't on a walkable tile", to.Level.Name))
+			}
+			if !canWalk(to.Level, to.Pos) {
+				problems = append(problems, posProblem(file, pos, false, "portal leads to %s %d,%d which isn't walkable", to.Level.Name, to.X, to.Y))
+			}
+			entrances[to.Level] = append(entrances[to.Level], to.Pos)
+
+			oneWay := true
+			for _, back := range to.Level.Portals {
+				if back.Level == level {
+					oneWay = false
+					break
+				}
+			}
+			if oneWay {
+				problems = append(problems, posProblem(file, pos, true, "one-way portal, nothing on %s leads back to %s", to.Level.Name, name))
+			}
+		}
+	}
+
+	for _, name := range names {
+		level := levels[name]
+		file := files[level]
+		if len(entrances[level]) == 0 {
+			problems = append(problems, Problem{File: file, Msg: "level can't be reached from the player start or any portal"})
+			continue
+		}
+
+		reached := level.reachable(entrances[level])
+		// report each unreachable region once, at its top left tile
+		for y, row := range level.Map {
+			for x := range row {
+				pos := Pos{x, y}
+				if !isFloor(level, pos) || reached[pos] {
+					continue
+				}
+				region := level.reachable([]Pos{pos})
+				for p := range region {
+					reached[p] = true
+				}
+				problems = append(problems, posProblem(file, pos, false, "%d floor tiles can't be reached", len(region)))
+			}
+		}
+	}
+
+	return problems
+}
+
+// CheckAtlas makes sure every monster and item in maps has a sprite in the
+// ui's atlas index, atlasName is only used in the reported problems
+func CheckAtlas(maps fs.FS, atlas io.Reader, atlasName string) []Problem {
+	var problems []Problem
+	monsters, err := LoadMonsterDefs(maps)
+	if err != nil {
+		return []Problem{{File: monstersFile, Msg: err.Error()}}
+	}
+	items, err := LoadItemCatalog(maps)
+	if err != nil {
+		return []Problem{{File: itemsFile, Msg: err.Error()}}
+	}
+
+	sprites := make(map[rune]bool)
+	scanner := bufio.NewScanner(atlas)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		r, _ := utf8.DecodeRuneInString(line)
+		sprites[r] = true
+	}
+	if scanner.Err() != nil {
+		return []Problem{{File: atlasName, Msg: scanner.Err().Error()}}
+	}
+
+	glyphs := make([]rune, 0, len(monsters))
+	for glyph := range monsters {
+		glyphs = append(glyphs, glyph)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	for _, glyph := range glyphs {
+		def := monsters[glyph]
+		if !sprites[def.Sprite] {
+			problems = append(problems, Problem{File: atlasName, Msg: fmt.Sprintf("no sprite %q for monster %s", def.Sprite, def.Name)})
+		}
+	}
+
+	ids := make([]string, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		def := items[id]
+		if !sprites[def.Glyph] {
+			problems = append(problems, Problem{File: atlasName, Msg: fmt.Sprintf("no sprite %q for item %s", def.Glyph, def.ID)})
+		}
+	}
+	return problems
+}
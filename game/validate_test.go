@@ -0,0 +1,88 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// two rooms joined by portals both ways, nothing wrong with them
+func goodMaps() map[string]string {
+	return map[string]string{
+		"room.map": "#####\n#@..#\n#####\n",
+		"hall.map": "####\n#..#\n####\n",
+	}
+}
+
+const goodWorld = "room\nroom,3,1,hall,1,1\nhall,2,1,room,2,1"
+
+// has is whether problems has one with msg in it, at line and col when they aren't 0
+func has(problems []Problem, warning bool, line, col int, msg string) bool {
+	for _, p := range problems {
+		if p.Warning == warning && strings.Contains(p.Msg, msg) && (line == 0 || p.Line == line) && (col == 0 || p.Col == col) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCheckMapsGood(t *testing.T) {
+	problems := CheckMaps(mapDir(goodWorld, goodMaps()))
+	if len(problems) != 0 {
+		t.Errorf("problems with good maps: %v", problems)
+	}
+}
+
+func TestCheckMaps(t *testing.T) {
+	tests := []struct {
+		name    string
+		world   string
+		change  map[string]string
+		warning bool
+		// where the problem is, 0 for anywhere
+		line, col int
+		msg       string
+	}{
+		{"unknown glyph", goodWorld, map[string]string{"hall.map": "####\n#.%#\n####\n"}, false, 2, 3, "unknown map character '%'"},
+		{"unknown glyph after a multibyte one", goodWorld, map[string]string{"hall.map": "#####\n#.é.#\n#####\n"}, false, 2, 3, "unknown map character 'é'"},
+		{"unreachable floor", goodWorld, map[string]string{"room.map": "#######\n#@..#.#\n#######\n"}, false, 2, 6, "1 floor tiles can't be reached"},
+		{"hole in the wall", goodWorld, map[string]string{"hall.map": "####\n#..\n####\n"}, false, 2, 3, "floor opens onto empty space"},
+		{"portal to a level that doesn't exist", "room\nroom,3,1,cellar,1,1", nil, false, 2, 0, `no level named "cellar"`},
+		{"portal into a wall", "room\nroom,3,1,hall,0,0\nhall,2,1,room,2,1", nil, false, 2, 4, "leads to hall 0,0 which isn't walkable"},
+		{"one-way portal", "room\nroom,3,1,hall,1,1", nil, true, 2, 4, "one-way portal"},
+		{"level nobody gets to", "room", nil, false, 0, 0, "level can't be reached"},
+		{"no player start", goodWorld, map[string]string{"room.map": "#####\n#...#\n#####\n"}, false, 0, 0, "no map has a player start"},
+		{"two player starts", goodWorld, map[string]string{"hall.map": "####\n#.@#\n####\n"}, false, 2, 3, "more than one place"},
+		{"player start after a multibyte glyph", goodWorld, map[string]string{
+			"hall.map":     "#####\n#.λ@#\n#####\n",
+			"monsters.txt": "λ, Lich, 80, 6, 1, 8, L\n",
+		}, false, 2, 4, "more than one place"},
+	}
+	for _, tt := range tests {
+		files := goodMaps()
+		for name, data := range tt.change {
+			files[name] = data
+		}
+		problems := CheckMaps(mapDir(tt.world, files))
+		if !has(problems, tt.warning, tt.line, tt.col, tt.msg) {
+			t.Errorf("%s: got %v, want %q at %d:%d", tt.name, problems, tt.msg, tt.line, tt.col)
+		}
+	}
+}
+
+func TestCheckAtlas(t *testing.T) {
+	maps := mapDir(goodWorld, goodMaps())
+	maps["monsters.txt"] = &fstest.MapFile{Data: []byte("B, Bat, 50, 1, 1.5, 10, B\nS, Spider, 100, 5, 1.1, 10, x\n")}
+	maps["items.txt"] = &fstest.MapFile{Data: []byte("sword, Sword, s, weapon, weapon, 0, Sharp., , attack=5\npotion, Potion, p, consumable, , 10, Red., heal\n")}
+
+	problems := CheckAtlas(maps, strings.NewReader("B 1,2,1\r\ns 3,4,1\r\n"), "atlas-index.txt")
+	want := []string{`no sprite 'x' for monster Spider`, `no sprite 'p' for item potion`}
+	if len(problems) != len(want) {
+		t.Fatalf("got %v, want %v", problems, want)
+	}
+	for i, w := range want {
+		if problems[i].Msg != w || problems[i].File != "atlas-index.txt" || problems[i].Warning {
+			t.Errorf("problem %d is %v, want the error %q", i, problems[i], w)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ViewKind is what a window keeps in the middle of the screen
+type ViewKind int
+
+const (
+	FollowPlayer ViewKind = iota
+	FollowMonster
+	FixedRegion
+)
+
+// View describes what a window shows. The zero View follows the player around
+// whichever level they're on, the way a single window always has
+type View struct {
+	Kind ViewKind
+	// the level to show, empty for whichever one the player is on
+	Level string
+	// the name of the monster a FollowMonster view watches. It sticks with the same one
+	// while it lives, then moves on to the nearest other monster with the name
+	Monster string
+	// the tile a FixedRegion view is centered on
+	Center Pos
+	// shows the whole map and everything on it rather than what the player has seen,
+	// other levels and monsters out of sight are mostly dark without it
+	Reveal bool
+}
+
+// Frame is what a window gets to draw after every turn
+type Frame struct {
+	Level *Level
+	// the tile to keep in the middle of the window
+	Center Pos
+	// whether the player is on Level, their position and ground items mean nothing on
+	// any other
+	HasPlayer bool
+	Reveal    bool
+	// a copy of Level taken for remote windows, see NewRemoteWindow
+	Snapshot *Snapshot
+}
+
+// Window is one frontend's view on the game. The game sends it a Frame after every turn
+// and closes Frames once the window is closed. Frames never block the game: a window
+// that's slow to read only ever finds the latest one waiting
+type Window struct {
+	View   View
+	frames chan *Frame
+
+	// the monster a FollowMonster view is on, and where the view was last centered
+	following *Monster
+	center    Pos
+	// frames carry a Snapshot
+	remote bool
+}
+
+// NewWindow makes a window on view. Send it in an OpenWindow input to have the game
+// start drawing to it while it runs, NewGame makes the ones there are at the start
+func NewWindow(view View) *Window {
+	return &Window{View: view, frames: make(chan *Frame, 1)}
+}
+
+func (w *Window) Frames() <-chan *Frame {
+	return w.frames
+}
+
+// Send swaps whatever frame is still waiting for f. A window has one sender, the game or
+// whatever stands in for it like a network client, so once the stale one is gone there's room
+func (w *Window) Send(f *Frame) {
+	select {
+	case <-w.frames:
+	default:
+	}
+	w.frames <- f
+}
+
+// Close closes Frames, its sender mustn't send any more
+func (w *Window) Close() {
+	close(w.frames)
+}
+
+// frame works out what w shows this turn
+func (game *Game) frame(w *Window) *Frame {
+	level := game.CurrentLevel
+	if w.View.Level != "" && game.Levels[w.View.Level] != nil {
+		level = game.Levels[w.View.Level]
+	}
+	f := &Frame{Level: level, HasPlayer: level == game.CurrentLevel, Reveal: w.View.Reveal}
+	if w.remote {
+		f.Snapshot = NewSnapshot(level)
+	}
+
+	switch w.View.Kind {
+	case FollowMonster:
+		if m := w.follow(level); m != nil {
+			w.center = m.Pos
+		}
+	case FixedRegion:
+		w.center = w.View.Center
+	default:
+		if f.HasPlayer {
+			w.center = level.Player.Pos
+		} else {
+			// the player isn't there to follow, show the middle of the map
+			w.center = Pos{len(level.Map[0]) / 2, len(level.Map) / 2}
+		}
+	}
+	f.Center = w.center
+	return f
+}
+
+// follow is the monster w watches on level, nil when there are none of its name left.
+// It's dropped once it dies or the level is loaded again, the nearest one takes over
+func (w *Window) follow(level *Level) *Monster {
+	m := w.following
+	if m != nil && level.Monsters[m.Pos] == m {
+		return m
+	}
+	w.following = nil
+	best := 0
+	for pos, m := range level.Monsters {
+		if m.Name != w.View.Monster {
+			continue
+		}
+		d := abs(pos.X-w.center.X) + abs(pos.Y-w.center.Y)
+		// ties go to the top left so the same one is picked every run
+		if w.following == nil || d < best || (d == best && (pos.Y < w.following.Y || (pos.Y == w.following.Y && pos.X < w.following.X))) {
+			w.following, best = m, d
+		}
+	}
+	return w.following
+}
+
+func (game *Game) sendFrames() {
+	for _, w := range game.Windows {
+		w.Send(game.frame(w))
+	}
+}
+
+func (game *Game) openWindow(w *Window) {
+	game.Windows = append(game.Windows, w)
+	w.Send(game.frame(w))
+}
+
+// closeWindow stops drawing to w and closes its Frames, closing one twice does nothing
+func (game *Game) closeWindow(w *Window) {
+	for i, open := range game.Windows {
+		if open == w {
+			w.Close()
+			game.Windows = append(game.Windows[:i], game.Windows[i+1:]...)
+			return
+		}
+	}
+}
+
+// ParseView reads a view from space separated fields, in any order:
+//
+//	player          follow the player, the default
+//	monster=Dragon  follow a monster with that name
+//	at=x,y          stay centered on a tile
+//	level=level2    show that level instead of the player's
+//	reveal          show everything, not just what the player has seen
+func ParseView(s string) (View, error) {
+	var view View
+	for _, field := range strings.Fields(s) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "player":
+			view.Kind = FollowPlayer
+		case "monster":
+			if value == "" {
+				return view, fmt.Errorf("view %q: monster needs a name", s)
+			}
+			view.Kind = FollowMonster
+			view.Monster = value
+		case "at":
+			xs, ys, found := strings.Cut(value, ",")
+			x, errX := strconv.Atoi(xs)
+			y, errY := strconv.Atoi(ys)
+			if !found || errX != nil || errY != nil {
+				return view, fmt.Errorf("view %q: at needs a tile like at=10,5", s)
+			}
+			view.Kind = FixedRegion
+			view.Center = Pos{x, y}
+		case "level":
+			if value == "" {
+				return view, fmt.Errorf("view %q: level needs a name", s)
+			}
+			view.Level = value
+		case "reveal":
+			view.Reveal = true
+		default:
+			return view, fmt.Errorf("view %q: unknown field %q", s, field)
+		}
+	}
+	return view, nil
+}
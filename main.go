@@ -8,33 +8,154 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
 	"runtime"
+	"sync"
 
+	"github.com/gorillana/rpg/dungeon"
 	"github.com/gorillana/rpg/game"
+	"github.com/gorillana/rpg/netplay"
 	"github.com/gorillana/rpg/ui2d"
+	"github.com/gorillana/rpg/uiterm"
 )
 
 // Windows and Linux machines
 func main() {
-	game := game.NewGame(1)
+	frontend := flag.String("ui", "2d", "frontend to use: 2d (SDL window) or term (ANSI terminal)")
+	seed := flag.Int64("seed", 0, "play a generated dungeon from this seed instead of the maps")
+	mapsDir := flag.String("maps", "", "directory to load .map files and world.txt from instead of the built in maps")
+	diagonal := flag.Bool("diagonal", false, "let the player and monsters move diagonally")
+	serve := flag.String("serve", "", "also let others join the game over TCP on this address, like :4000")
+	connect := flag.String("connect", "", "join a game served on this address instead of starting one")
+	var views []game.View
+	var viewArgs []string
+	flag.Func("view", "open a window on a view like \"monster=Dragon reveal\", once per window (default player)", func(s string) error {
+		view, err := game.ParseView(s)
+		views = append(views, view)
+		viewArgs = append(viewArgs, s)
+		return err
+	})
+	flag.Parse()
+	if len(views) == 0 {
+		views = []game.View{{}}
+		viewArgs = []string{""}
+	}
+	if *frontend == "term" && len(views) > 1 {
+		fmt.Fprintln(os.Stderr, "the terminal frontend only has room for one view")
+		os.Exit(1)
+	}
+
+	if *connect != "" {
+		// a connection for each window, the server works out what they show. The frontends
+		// are waited for too, the terminal one has to be put back out of raw mode after its
+		// window closes
+		var running sync.WaitGroup
+		var windows []ui2d.Window
+		for _, view := range viewArgs {
+			c, err := netplay.Dial(*connect, view)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			running.Add(1)
+			go func() {
+				defer running.Done()
+				err := c.Run()
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+			}()
+			if *frontend == "term" {
+				running.Add(1)
+				go func() {
+					defer running.Done()
+					uiterm.NewUI(c.InputChan, c.Window).Run()
+				}()
+			}
+			windows = append(windows, ui2d.Window{Input: c.InputChan, View: c.Window})
+		}
+		if *frontend != "term" {
+			runSDL(windows)
+		}
+		running.Wait()
+		return
+	}
+
+	var maps fs.FS
+	if *mapsDir != "" {
+		maps = os.DirFS(*mapsDir)
+	}
+	var g *game.Game
+	var err error
+	if *seed != 0 {
+		g = game.NewGameFromLevel(len(views), func() *game.Level {
+			return dungeon.Generate(*seed, 80, 50)
+		})
+	} else {
+		g, err = game.NewGame(len(views), maps)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	g.SetDiagonal(*diagonal)
+
+	for i, view := range views {
+		if view.Level != "" && g.Levels[view.Level] == nil {
+			fmt.Fprintf(os.Stderr, "view %d: no level named %s\n", i+1, view.Level)
+			os.Exit(1)
+		}
+		g.Windows[i].View = view
+	}
+
+	if *serve != "" {
+		l, err := net.Listen("tcp", *serve)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("Serving on", l.Addr())
+		go netplay.Serve(l, g)
+	}
+
+	if *frontend == "term" {
+		go uiterm.NewUI(g.InputChan, g.Windows[0]).Run()
+		g.Run()
+		return
+	}
+	var windows []ui2d.Window
+	for _, window := range g.Windows {
+		windows = append(windows, ui2d.Window{Input: g.InputChan, View: window})
+	}
+	go g.Run()
+	runSDL(windows)
+}
+
+// SDL has to be called from the main thread, so main keeps it and the game runs alongside
+func init() {
+	runtime.LockOSThread()
+}
 
-	for i := 0; i < 1; i++ {
-		go func(i int) {
-			// calls LockOSThread inside go routine in order to keep the sdl code called in one thread
-			runtime.LockOSThread()
-			ui := ui2d.NewUI(game.InputChan, game.LevelChans[i])
-			ui.Run()
-		}(i)
+// runSDL opens an SDL window for each of windows and returns once they're all closed
+func runSDL(windows []ui2d.Window) {
+	err := ui2d.Run(windows...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	game.Run()
 }
 
 // Mac machines
 // func main() {
-//game := game.NewGame(1, "game/maps/level1.map")
+//game := game.NewGame(1, nil)
 //go func() {
 //	game.Run()
 //}()
-// ui := ui2d.NewUI(game.InputChan, game.LevelChans[0])
+// ui := ui2d.NewUI(game.InputChan, game.Windows[0])
 // ui.Run()
 //}
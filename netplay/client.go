@@ -0,0 +1,143 @@
+package netplay
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/gorillana/rpg/game"
+)
+
+// Client stands in for a game on the other end of a connection. Frontends use it the
+// way they'd use a local one: inputs go in InputChan and frames come out of Window
+type Client struct {
+	InputChan chan *game.Input
+	Window    *game.Window
+
+	conn net.Conn
+	// the levels built from the last two frames, an input's item is in whichever one
+	// the frontend was drawing
+	mu     sync.Mutex
+	levels [2]*game.Level
+}
+
+// Dial connects to a server at addr and asks for a window on view, written the way
+// game.ParseView reads it
+func Dial(addr string, view string) (*Client, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	err = json.NewEncoder(conn).Encode(message{Type: "hello", View: view})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Client{InputChan: make(chan *game.Input), Window: game.NewWindow(game.View{}), conn: conn}, nil
+}
+
+// Run passes frames to Window and inputs to the server until one side hangs up, quitting
+// or closing the window hangs up this one. Window is closed when it returns
+func (c *Client) Run() error {
+	sent := make(chan error, 1)
+	go func() {
+		sent <- c.sendInputs()
+		c.conn.Close()
+	}()
+	err := c.readFrames()
+	c.Window.Close()
+	c.conn.Close()
+	select {
+	case err := <-sent:
+		// we hung up, reading was cut short on purpose
+		return err
+	default:
+	}
+	if err == io.EOF {
+		return errors.New("the server hung up")
+	}
+	return err
+}
+
+func (c *Client) readFrames() error {
+	dec := json.NewDecoder(c.conn)
+	var last *game.Level
+	for {
+		var msg message
+		err := dec.Decode(&msg)
+		if err != nil {
+			return err
+		}
+		switch msg.Type {
+		case "error":
+			return errors.New(msg.Error)
+		case "frame":
+			if msg.Level == nil {
+				return errors.New("frame without a level")
+			}
+			level, err := msg.Level.Level(last)
+			if err != nil {
+				return err
+			}
+			last = level
+			c.mu.Lock()
+			c.levels[0], c.levels[1] = level, c.levels[0]
+			c.mu.Unlock()
+			c.Window.Send(&game.Frame{Level: level, Center: game.Pos{X: msg.X, Y: msg.Y}, HasPlayer: msg.HasPlayer, Reveal: msg.Reveal})
+		}
+	}
+}
+
+func (c *Client) sendInputs() error {
+	enc := json.NewEncoder(c.conn)
+	for input := range c.InputChan {
+		switch input.Typ {
+		case game.QuitGame, game.CloseWindow:
+			return nil
+		case game.OpenWindow:
+			// a connection has the one window, nobody will draw to this one
+			input.Window.Close()
+			continue
+		}
+		msg, ok := c.inputMessage(input)
+		if !ok {
+			continue
+		}
+		err := enc.Encode(msg)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inputMessage is input as the server reads it, false for ones it doesn't take
+// or that need an item that isn't where they can reach it in either of the last two frames
+func (c *Client) inputMessage(input *game.Input) (message, bool) {
+	name, ok := inputName(input.Typ)
+	if !ok {
+		return message{}, false
+	}
+	msg := message{Type: "input", Input: name, X: input.Pos.X, Y: input.Pos.Y}
+	from := game.TakesItem(input.Typ)
+	if from == "" {
+		return msg, true
+	}
+	if input.Item == nil {
+		return message{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, level := range c.levels {
+		if level == nil {
+			continue
+		}
+		if ref, found := level.RefTo(input.Item); found && ref.In == from {
+			msg.Item = &ref
+			return msg, true
+		}
+	}
+	return message{}, false
+}
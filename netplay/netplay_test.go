@@ -0,0 +1,189 @@
+package netplay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gorillana/rpg/game"
+)
+
+// serveGame runs a new game and serves it on a loopback port until the test ends
+func serveGame(t *testing.T) (*game.Game, net.Listener) {
+	t.Helper()
+	g, err := game.NewGame(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan bool)
+	go func() {
+		g.Run()
+		close(done)
+	}()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go Serve(l, g)
+	t.Cleanup(func() {
+		l.Close()
+		// the game quits by itself once the last window closes
+		select {
+		case g.InputChan <- &game.Input{Typ: game.QuitGame}:
+		case <-done:
+		}
+	})
+	return g, l
+}
+
+func next(t *testing.T, w *game.Window) *game.Frame {
+	t.Helper()
+	select {
+	case f, ok := <-w.Frames():
+		if !ok {
+			t.Fatal("window closed")
+		}
+		return f
+	case <-time.After(5 * time.Second):
+		t.Fatal("no frame after 5s")
+	}
+	return nil
+}
+
+// a client and the local window it should agree with
+type pair struct {
+	view   string
+	client *Client
+	local  *game.Window
+	// what the client built from the last frame, its items are the ones it can point at
+	level *game.Level
+}
+
+// check waits for this turn's frames and compares them
+func (p *pair) check(t *testing.T, turn int) {
+	t.Helper()
+	local, remote := next(t, p.local), next(t, p.client.Window)
+	if local.Center != remote.Center || local.HasPlayer != remote.HasPlayer || local.Reveal != remote.Reveal {
+		t.Fatalf("turn %d, view %q: served %v %v %v, client got %v %v %v", turn, p.view,
+			local.Center, local.HasPlayer, local.Reveal, remote.Center, remote.HasPlayer, remote.Reveal)
+	}
+	want, err := json.Marshal(local.Snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(game.NewSnapshot(remote.Level))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Fatalf("turn %d, view %q: the client's level doesn't match\nserved %s\nbuilt  %s", turn, p.view, want, got)
+	}
+	p.level = remote.Level
+}
+
+// Plays random turns through a client and checks after each one that every client built
+// the same level from the wire as a local window with its view was sent
+func TestClientsMatchServer(t *testing.T) {
+	for _, seed := range []int64{1, 7} {
+		t.Run(fmt.Sprint("seed ", seed), func(t *testing.T) {
+			g, l := serveGame(t)
+			var pairs []*pair
+			for _, v := range []string{"", "level=level2 monster=Dragon reveal"} {
+				view, err := game.ParseView(v)
+				if err != nil {
+					t.Fatal(err)
+				}
+				p := &pair{view: v, local: game.NewRemoteWindow(view)}
+				g.InputChan <- &game.Input{Typ: game.OpenWindow, Window: p.local}
+				p.client, err = Dial(l.Addr().String(), v)
+				if err != nil {
+					t.Fatal(err)
+				}
+				go p.client.Run()
+				t.Cleanup(func() { p.client.InputChan <- &game.Input{Typ: game.QuitGame} })
+				pairs = append(pairs, p)
+			}
+			for _, p := range pairs {
+				p.check(t, 0)
+			}
+
+			rng := rand.New(rand.NewSource(seed))
+			moves := []game.InputType{game.Up, game.Down, game.Left, game.Right}
+			for turn := 1; turn <= 300; turn++ {
+				input := &game.Input{Typ: moves[rng.Intn(len(moves))]}
+				switch rng.Intn(10) {
+				case 0:
+					input = &game.Input{Typ: game.TakeAll}
+				case 1:
+					// equip from the bag through the client, which has to point at the item by where it is
+					if bag := pairs[0].level.Player.Items; len(bag) > 0 {
+						input = &game.Input{Typ: game.EquipItem, Item: bag[rng.Intn(len(bag))]}
+					}
+				}
+				pairs[0].client.InputChan <- input
+				for _, p := range pairs {
+					p.check(t, turn)
+				}
+			}
+		})
+	}
+}
+
+// Inputs without an item where they need one, with one somewhere they can't reach or
+// that only the server may send are dropped, and the game carries on
+func TestBadInputs(t *testing.T) {
+	_, l := serveGame(t)
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	fmt.Fprintln(conn, `{"type":"hello"}`)
+	frames := bufio.NewScanner(conn)
+	frames.Buffer(nil, 1<<20)
+	if !frames.Scan() {
+		t.Fatal("no first frame:", frames.Err())
+	}
+
+	for _, bad := range []string{
+		`{"type":"input","input":"take"}`,
+		`{"type":"input","input":"equip"}`,
+		`{"type":"input","input":"use"}`,
+		`{"type":"input","input":"drop"}`,
+		`{"type":"input","input":"unequip"}`,
+		`{"type":"input","input":"equip","item":{"in":"ground"}}`,
+		`{"type":"input","input":"take","item":{"in":"bag"}}`,
+		`{"type":"input","input":"save"}`,
+		`{"type":"input","input":"load"}`,
+		`{"type":"input","input":"restart"}`,
+		`{"type":"hello"}`,
+	} {
+		fmt.Fprintln(conn, bad)
+	}
+	// well formed but pointing at nothing, the game turns these down itself. Each gets a
+	// frame, waiting for it keeps the next one from replacing it
+	for _, input := range []string{
+		`{"type":"input","input":"take","item":{"in":"ground","index":99}}`,
+		`{"type":"input","input":"unequip","item":{"in":"worn","index":-1}}`,
+		`{"type":"input","input":"up"}`,
+	} {
+		fmt.Fprintln(conn, input)
+		if !frames.Scan() {
+			t.Fatalf("no frame after %s: %v", input, frames.Err())
+		}
+		var msg message
+		err := json.Unmarshal(frames.Bytes(), &msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type != "frame" {
+			t.Fatalf("got %s after %s", frames.Bytes(), input)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package netplay puts a running game on the network so others can join it from another
+// machine. Each connection gets its own window on the game, and everyone who joins plays
+// the same player.
+//
+// The protocol is JSON, one message per line. A client starts with
+//
+//	{"type":"hello","view":"monster=Dragon reveal"}
+//
+// where view is read by game.ParseView and left out follows the player. Then it sends
+// inputs whenever it likes
+//
+//	{"type":"input","input":"up"}
+//	{"type":"input","input":"equip","item":{"in":"bag","index":2}}
+//	{"type":"input","input":"shutdoor","x":10,"y":4}
+//
+// take, drop, equip, unequip and use point at their item with a game.ItemRef, which has
+// to be on the ground for take, worn for unequip and in the bag for the rest. x and y are
+// the door for shutdoor. Inputs that aren't like this are dropped. The server
+// sends a frame after every turn
+//
+//	{"type":"frame","x":12,"y":3,"hasPlayer":true,"level":{ snapshot }}
+//
+// x and y are the tile to center on and level is a game.Snapshot, every tile the first
+// time a level is sent and only the ones that changed after that. A bad hello gets
+// {"type":"error","error":"..."} and the connection closed. Hanging up closes the window
+package netplay
+
+import (
+	"fmt"
+
+	"github.com/gorillana/rpg/game"
+)
+
+type message struct {
+	Type string `json:"type"`
+	// hello
+	View string `json:"view,omitempty"`
+	// input
+	Input string        `json:"input,omitempty"`
+	Item  *game.ItemRef `json:"item,omitempty"`
+	// the tile an input is aimed at, or the one a frame is centered on
+	X int `json:"x,omitempty"`
+	Y int `json:"y,omitempty"`
+	// frame
+	HasPlayer bool           `json:"hasPlayer,omitempty"`
+	Reveal    bool           `json:"reveal,omitempty"`
+	Level     *game.Snapshot `json:"level,omitempty"`
+	// error
+	Error string `json:"error,omitempty"`
+}
+
+// the inputs a client can send. Quitting and opening or closing windows aren't among
+// them, a client hangs up instead. Saving, loading and restarting are up to whoever runs
+// the server
+var inputNames = map[string]game.InputType{
+	"up":        game.Up,
+	"down":      game.Down,
+	"left":      game.Left,
+	"right":     game.Right,
+	"upleft":    game.UpLeft,
+	"upright":   game.UpRight,
+	"downleft":  game.DownLeft,
+	"downright": game.DownRight,
+	"takeall":   game.TakeAll,
+	"shutdoor":  game.ShutDoor,
+	"take":      game.TakeItem,
+	"drop":      game.DropItem,
+	"equip":     game.EquipItem,
+	"unequip":   game.UnequipItem,
+	"use":       game.UseItem,
+}
+
+func inputName(typ game.InputType) (string, bool) {
+	for name, t := range inputNames {
+		if t == typ {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// toInput is the game input an input message asks for. Inputs that act on an item have
+// to point at one where it can be, take on the ground, unequip among what's worn and the
+// rest in the bag
+func (msg *message) toInput() (*game.Input, error) {
+	typ, ok := inputNames[msg.Input]
+	if !ok {
+		return nil, fmt.Errorf("unknown input %q", msg.Input)
+	}
+	input := &game.Input{Typ: typ, Pos: game.Pos{X: msg.X, Y: msg.Y}}
+	if from := game.TakesItem(typ); from != "" {
+		if msg.Item == nil || msg.Item.In != from {
+			return nil, fmt.Errorf("%s needs an item in %s", msg.Input, from)
+		}
+		ref := *msg.Item
+		input.Ref = &ref
+	}
+	return input, nil
+}
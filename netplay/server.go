@@ -0,0 +1,85 @@
+package netplay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/gorillana/rpg/game"
+)
+
+// Serve gives every connection to l its own remote window on g until l is closed.
+// g has to be running, windows are opened and inputs sent through its InputChan
+func Serve(l net.Listener, g *game.Game) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go serve(conn, g)
+	}
+}
+
+func serve(conn net.Conn, g *game.Game) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+
+	var hello message
+	err := dec.Decode(&hello)
+	if err != nil {
+		return
+	}
+	if hello.Type != "hello" {
+		json.NewEncoder(conn).Encode(message{Type: "error", Error: fmt.Sprintf("expected hello, got %q", hello.Type)})
+		return
+	}
+	view, err := game.ParseView(hello.View)
+	if err != nil {
+		json.NewEncoder(conn).Encode(message{Type: "error", Error: err.Error()})
+		return
+	}
+
+	window := game.NewRemoteWindow(view)
+	g.InputChan <- &game.Input{Typ: game.OpenWindow, Window: window}
+	written := make(chan bool)
+	go func() {
+		writeFrames(conn, window)
+		close(written)
+	}()
+
+	// a bad input is dropped, the client may be newer than we are
+	for {
+		var msg message
+		err := dec.Decode(&msg)
+		if err != nil {
+			break
+		}
+		if msg.Type != "input" {
+			continue
+		}
+		input, err := msg.toInput()
+		if err != nil {
+			fmt.Println(conn.RemoteAddr(), err)
+			continue
+		}
+		g.InputChan <- input
+	}
+	g.InputChan <- &game.Input{Typ: game.CloseWindow, Window: window}
+	<-written
+}
+
+// writeFrames sends window's frames until the game closes it, only sending the tiles
+// that changed since the frame before. It hangs up when the client can't be written to
+func writeFrames(conn net.Conn, window *game.Window) {
+	enc := json.NewEncoder(conn)
+	var last *game.Snapshot
+	for f := range window.Frames() {
+		msg := message{Type: "frame", X: f.Center.X, Y: f.Center.Y, HasPlayer: f.HasPlayer, Reveal: f.Reveal, Level: f.Snapshot.Diff(last)}
+		last = f.Snapshot
+		err := enc.Encode(msg)
+		if err != nil {
+			conn.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package pathfinding finds ways through anything that can say what's next to what.
+// The game, the map checker and the dungeon generator each describe their own idea of
+// where you can go as a Graph and share the searches here
+package pathfinding
+
+// Graph is the space being searched, N is a node in it, usually a game.Pos
+type Graph[N comparable] interface {
+	// Neighbors are the nodes one step from n, this is where passability rules go
+	Neighbors(n N) []N
+	// Cost is the price of stepping from a node to one of its neighbors, never negative
+	Cost(from, to N) int
+	// Heuristic guesses the cost from n to goal. A* only finds the cheapest path when
+	// it never guesses high, returning 0 turns it into Dijkstra
+	Heuristic(n, goal N) int
+}
+
+// AStar is the cheapest path from start to goal, both ends included, nil when there isn't one
+func AStar[N comparable](g Graph[N], start, goal N) []N {
+	frontier := NewQueue[N]()
+	frontier.Push(start, g.Heuristic(start, goal))
+	cameFrom := map[N]N{start: start}
+	costSoFar := map[N]int{start: 0}
+
+	for frontier.Len() > 0 {
+		current, _ := frontier.Pop()
+		if current == goal {
+			return walkBack(cameFrom, start, goal)
+		}
+
+		for _, next := range g.Neighbors(current) {
+			newCost := costSoFar[current] + g.Cost(current, next)
+			old, seen := costSoFar[next]
+			if !seen || newCost < old {
+				costSoFar[next] = newCost
+				cameFrom[next] = current
+				frontier.Push(next, newCost+g.Heuristic(next, goal))
+			}
+		}
+	}
+	return nil
+}
+
+func walkBack[N comparable](cameFrom map[N]N, start, goal N) []N {
+	path := []N{goal}
+	for p := goal; p != start; {
+		p = cameFrom[p]
+		path = append(path, p)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// Dijkstra is the cost of the cheapest way to every node reachable from the nearest of
+// sources. Nodes it can't reach aren't in the map. The heuristic isn't used
+func Dijkstra[N comparable](g Graph[N], sources ...N) map[N]int {
+	frontier := NewQueue[N]()
+	dist := make(map[N]int)
+	for _, s := range sources {
+		dist[s] = 0
+		frontier.Push(s, 0)
+	}
+
+	for frontier.Len() > 0 {
+		current, d := frontier.Pop()
+		for _, next := range g.Neighbors(current) {
+			newDist := d + g.Cost(current, next)
+			old, seen := dist[next]
+			if !seen || newDist < old {
+				dist[next] = newDist
+				frontier.Push(next, newDist)
+			}
+		}
+	}
+	return dist
+}
+
+// BFS visits every node reachable from starts in order of how many steps away it is,
+// ignoring cost. It stops at the first node visit returns true for and returns it,
+// false when visit never does
+func BFS[N comparable](g Graph[N], starts []N, visit func(n N) bool) (N, bool) {
+	frontier := make([]N, 0, 8)
+	visited := make(map[N]bool)
+	for _, s := range starts {
+		if !visited[s] {
+			frontier = append(frontier, s)
+			visited[s] = true
+		}
+	}
+
+	for len(frontier) > 0 {
+		current := frontier[0]
+		frontier = frontier[1:]
+		if visit(current) {
+			return current, true
+		}
+		for _, next := range g.Neighbors(current) {
+			if !visited[next] {
+				frontier = append(frontier, next)
+				visited[next] = true
+			}
+		}
+	}
+	var none N
+	return none, false
+}
@@ -0,0 +1,305 @@
+package pathfinding
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type pos struct{ x, y int }
+
+// grid is a map drawn in strings: # is a wall, a digit is a tile that costs that much to
+// step onto and anything else costs 1
+type grid []string
+
+func (g grid) open(p pos) bool {
+	return p.y >= 0 && p.y < len(g) && p.x >= 0 && p.x < len(g[p.y]) && g[p.y][p.x] != '#'
+}
+
+// up, down, left, right, the order ties are broken in
+func (g grid) Neighbors(p pos) []pos {
+	var ns []pos
+	for _, n := range []pos{{p.x, p.y - 1}, {p.x, p.y + 1}, {p.x - 1, p.y}, {p.x + 1, p.y}} {
+		if g.open(n) {
+			ns = append(ns, n)
+		}
+	}
+	return ns
+}
+
+func (g grid) Cost(from, to pos) int {
+	if c := g[to.y][to.x]; c >= '0' && c <= '9' {
+		return int(c - '0')
+	}
+	return 1
+}
+
+func (g grid) Heuristic(p, goal pos) int {
+	return abs(p.x-goal.x) + abs(p.y-goal.y)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// find is where c is drawn in g
+func (g grid) find(c byte) pos {
+	for y, row := range g {
+		if x := strings.IndexByte(row, c); x >= 0 {
+			return pos{x, y}
+		}
+	}
+	panic("no " + string(c))
+}
+
+func (g grid) pathCost(path []pos) int {
+	cost := 0
+	for i := 1; i < len(path); i++ {
+		cost += g.Cost(path[i-1], path[i])
+	}
+	return cost
+}
+
+// S is the start and G the goal, the start is the goal when the cost is 0
+var searches = []struct {
+	name string
+	grid grid
+	// -1 when there's no way through
+	cost int
+}{
+	{"straight", grid{
+		"S...G",
+	}, 4},
+	{"around a wall", grid{
+		"S#G",
+		".#.",
+		"...",
+	}, 6},
+	{"through water is cheaper than the long way", grid{
+		"S3G",
+		".#.",
+		".#.",
+		".#.",
+		"...",
+	}, 4},
+	{"around water when it's dearer", grid{
+		"S9G",
+		"...",
+	}, 4},
+	{"walled off", grid{
+		"S.#..",
+		"..#.G",
+	}, -1},
+	{"already there", grid{
+		".G.",
+	}, 0},
+}
+
+// ends is where a search in g starts and ends
+func (g grid) ends(cost int) (start, goal pos) {
+	goal = g.find('G')
+	if cost == 0 {
+		return goal, goal
+	}
+	return g.find('S'), goal
+}
+
+func TestAStar(t *testing.T) {
+	for _, tt := range searches {
+		start, goal := tt.grid.ends(tt.cost)
+		path := AStar[pos](tt.grid, start, goal)
+		if tt.cost < 0 {
+			if path != nil {
+				t.Errorf("%s: found %v where there's no way through", tt.name, path)
+			}
+			continue
+		}
+		if len(path) == 0 || path[0] != start || path[len(path)-1] != goal {
+			t.Errorf("%s: %v doesn't go from %v to %v", tt.name, path, start, goal)
+			continue
+		}
+		for i := 1; i < len(path); i++ {
+			if tt.grid.Heuristic(path[i-1], path[i]) != 1 || !tt.grid.open(path[i]) {
+				t.Errorf("%s: %v steps from %v to %v", tt.name, path, path[i-1], path[i])
+			}
+		}
+		if got := tt.grid.pathCost(path); got != tt.cost {
+			t.Errorf("%s: path %v costs %d, want %d", tt.name, path, got, tt.cost)
+		}
+	}
+}
+
+func TestDijkstra(t *testing.T) {
+	for _, tt := range searches {
+		start, goal := tt.grid.ends(tt.cost)
+		dist := Dijkstra[pos](tt.grid, start)
+		got, found := dist[goal]
+		switch {
+		case tt.cost < 0 && found:
+			t.Errorf("%s: reached the goal for %d where there's no way through", tt.name, got)
+		case tt.cost >= 0 && got != tt.cost:
+			t.Errorf("%s: cost %d, want %d", tt.name, got, tt.cost)
+		}
+	}
+
+	// several sources, each tile is as far as the nearest one
+	g := grid{
+		"S...3...S",
+	}
+	dist := Dijkstra[pos](g, pos{0, 0}, pos{8, 0})
+	want := []int{0, 1, 2, 3, 6, 3, 2, 1, 0}
+	for x, w := range want {
+		if dist[pos{x, 0}] != w {
+			t.Errorf("two sources: %d,0 is %d away, want %d", x, dist[pos{x, 0}], w)
+		}
+	}
+}
+
+// weighted is a graph with a cost on each edge, from each node to its neighbors
+type weighted map[string]map[string]int
+
+func (g weighted) Neighbors(n string) []string {
+	var ns []string
+	for next := range g[n] {
+		ns = append(ns, next)
+	}
+	sort.Strings(ns)
+	return ns
+}
+
+func (g weighted) Cost(from, to string) int {
+	return g[from][to]
+}
+
+func (g weighted) Heuristic(n, goal string) int {
+	return 0
+}
+
+// A node first reached the dear way is moved up the queue when a cheaper way turns up,
+// instead of being searched on from at the old cost
+func TestDecreaseKey(t *testing.T) {
+	// c is first reached straight from s for 10, then through a and b for 3
+	g := weighted{
+		"s": {"a": 1, "c": 10},
+		"a": {"b": 1},
+		"b": {"c": 1},
+		"c": {"d": 1},
+	}
+	dist := Dijkstra[string](g, "s")
+	want := map[string]int{"s": 0, "a": 1, "b": 2, "c": 3, "d": 4}
+	if !reflect.DeepEqual(dist, want) {
+		t.Errorf("got %v, want %v", dist, want)
+	}
+	path := AStar[string](g, "s", "d")
+	if !reflect.DeepEqual(path, []string{"s", "a", "b", "c", "d"}) {
+		t.Errorf("path %v, want the long cheap way", path)
+	}
+}
+
+// Equally cheap paths are chosen the same way every time, by the order the graph lists
+// neighbors in
+func TestTieOrder(t *testing.T) {
+	g := grid{
+		"S..",
+		"...",
+		"..G",
+	}
+	// up and down are tried before left and right, so it heads down first
+	want := []pos{{0, 0}, {0, 1}, {0, 2}, {1, 2}, {2, 2}}
+	for i := 0; i < 20; i++ {
+		path := AStar[pos](g, g.find('S'), g.find('G'))
+		if !reflect.DeepEqual(path, want) {
+			t.Fatalf("run %d: %v, want %v", i, path, want)
+		}
+	}
+}
+
+func TestBFS(t *testing.T) {
+	g := grid{
+		"S.#",
+		"..#",
+		"#..",
+	}
+	var order []pos
+	_, found := BFS[pos](g, []pos{{0, 0}}, func(p pos) bool {
+		order = append(order, p)
+		return false
+	})
+	if found {
+		t.Error("found something when visit never said so")
+	}
+	want := []pos{{0, 0}, {0, 1}, {1, 0}, {1, 1}, {1, 2}, {2, 2}}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("visited %v, want %v", order, want)
+	}
+
+	// stops at the first match, duplicate starts are visited once
+	visits := 0
+	got, found := BFS[pos](g, []pos{{0, 0}, {0, 0}, {1, 0}}, func(p pos) bool {
+		visits++
+		return p.y == 1
+	})
+	if !found || got != (pos{0, 1}) || visits != 3 {
+		t.Errorf("got %v %v after %d visits, want {0 1} true after 3", got, found, visits)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	q := NewQueue[string]()
+	pushes := []struct {
+		value    string
+		priority int
+	}{
+		{"c", 5},
+		{"a", 3},
+		{"b", 3},
+		{"d", 7},
+		{"e", 1},
+		// decrease-key, d moves ahead of everything
+		{"d", 0},
+		// and increase-key, e moves behind c
+		{"e", 6},
+		// ties come out in the order they were first pushed, not the order they got there
+		{"f", 5},
+		{"c", 5},
+	}
+	for _, p := range pushes {
+		q.Push(p.value, p.priority)
+	}
+	if q.Len() != 6 {
+		t.Fatalf("%d queued, want 6, each value is only in once", q.Len())
+	}
+	if p, ok := q.Priority("e"); !ok || p != 6 {
+		t.Errorf("e is at %d %v, want 6 true", p, ok)
+	}
+	if _, ok := q.Priority("z"); ok {
+		t.Error("z was never queued")
+	}
+
+	var got []string
+	var priorities []int
+	for q.Len() > 0 {
+		v, p := q.Pop()
+		got = append(got, v)
+		priorities = append(priorities, p)
+	}
+	want := []string{"d", "a", "b", "c", "f", "e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("popped %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(priorities, []int{0, 3, 3, 5, 5, 6}) {
+		t.Errorf("priorities %v", priorities)
+	}
+
+	// popped values can be pushed again
+	q.Push("a", 1)
+	q.Push("b", 1)
+	q.Push("a", 1)
+	if v, _ := q.Pop(); v != "a" {
+		t.Errorf("popped %s, want a", v)
+	}
+}
@@ -0,0 +1,113 @@
+package pathfinding
+
+type queueItem[T comparable] struct {
+	value    T
+	priority int
+	// order it was first pushed in, equal priorities come out first in first out
+	seq int
+	// where it is in the heap, kept here so moving it about doesn't touch the index
+	at int
+}
+
+// Queue is a min-heap priority queue, lowest priority pops first. Each value is in it at
+// most once, pushing one that's already queued moves it to the new priority. Ties pop in
+// the order values were first pushed so anything built on it behaves the same every run
+type Queue[T comparable] struct {
+	items   []*queueItem[T]
+	index   map[T]*queueItem[T]
+	nextSeq int
+}
+
+func NewQueue[T comparable]() *Queue[T] {
+	return &Queue[T]{index: make(map[T]*queueItem[T])}
+}
+
+func (q *Queue[T]) Len() int {
+	return len(q.items)
+}
+
+// Push queues value, or changes its priority if it's already queued
+func (q *Queue[T]) Push(value T, priority int) {
+	if item, queued := q.index[value]; queued {
+		old := item.priority
+		item.priority = priority
+		if priority < old {
+			q.up(item.at)
+		} else {
+			q.down(item.at)
+		}
+		return
+	}
+	item := &queueItem[T]{value, priority, q.nextSeq, len(q.items)}
+	q.nextSeq++
+	q.items = append(q.items, item)
+	q.index[value] = item
+	q.up(item.at)
+}
+
+// Pop takes out the value with the lowest priority, the queue mustn't be empty
+func (q *Queue[T]) Pop() (T, int) {
+	top := q.items[0]
+	last := len(q.items) - 1
+	q.items[0] = q.items[last]
+	q.items = q.items[:last]
+	delete(q.index, top.value)
+	if last > 0 {
+		q.down(0)
+	}
+	return top.value, top.priority
+}
+
+// Priority is where value is in the queue, false if it isn't
+func (q *Queue[T]) Priority(value T) (int, bool) {
+	item, queued := q.index[value]
+	if !queued {
+		return 0, false
+	}
+	return item.priority, true
+}
+
+func (a *queueItem[T]) before(b *queueItem[T]) bool {
+	if a.priority != b.priority {
+		return a.priority < b.priority
+	}
+	return a.seq < b.seq
+}
+
+func (q *Queue[T]) set(i int, item *queueItem[T]) {
+	q.items[i] = item
+	item.at = i
+}
+
+// up and down carry the item at i to where it belongs, shifting the others along
+func (q *Queue[T]) up(i int) {
+	item := q.items[i]
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !item.before(q.items[parent]) {
+			break
+		}
+		q.set(i, q.items[parent])
+		i = parent
+	}
+	q.set(i, item)
+}
+
+func (q *Queue[T]) down(i int) {
+	item := q.items[i]
+	for {
+		smallest := i*2 + 1
+		if smallest >= len(q.items) {
+			break
+		}
+		if right := smallest + 1; right < len(q.items) && q.items[right].before(q.items[smallest]) {
+			smallest = right
+		}
+		if !q.items[smallest].before(item) {
+			break
+		}
+		q.set(i, q.items[smallest])
+		i = smallest
+	}
+	q.set(i, item)
+}
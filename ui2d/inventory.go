@@ -14,19 +14,22 @@ func (ui *ui) DrawInventory(level *game.Level) {
 	offset := int32(float64(invRect.H) * 0.05)
 
 	ui.renderer.Copy(ui.textureAtlas, &playerSrcRect, &sdl.Rect{X: invRect.X + invRect.X/4, Y: invRect.Y + offset, W: invRect.W / 2, H: invRect.H / 2})
-	ui.renderer.Copy(ui.slotBackground, nil, ui.getHelmetSlotRect())
-	if level.Player.Helmet != nil {
-		ui.renderer.Copy(ui.textureAtlas, &ui.textureIndex[level.Player.Helmet.Rune][0], ui.getHelmetSlotRect())
+	for i, slot := range equipSlots {
+		r := ui.getEquipSlotRect(i)
+		ui.renderer.Copy(ui.slotBackground, nil, r)
+		item := slot.item(&level.Player.Character)
+		if item != nil && item != ui.draggedItem {
+			ui.renderer.Copy(ui.textureAtlas, &ui.textureIndex[item.Rune][0], r)
+		}
 	}
-	ui.renderer.Copy(ui.slotBackground, nil, ui.getWeaponSlotRect())
-	if level.Player.Weapon != nil {
-		ui.renderer.Copy(ui.textureAtlas, &ui.textureIndex[level.Player.Weapon.Rune][0], ui.getWeaponSlotRect())
+	itemSize := int32(ItemSizeRatio * float32(ui.winWidth))
+	if ui.isEquipped(level, ui.draggedItem) {
+		ui.renderer.Copy(ui.textureAtlas, &ui.textureIndex[ui.draggedItem.Rune][0], &sdl.Rect{int32(ui.currentMouseState.pos.X), int32(ui.currentMouseState.pos.Y), itemSize, itemSize})
 	}
 
 	for i, item := range level.Player.Items {
 		itemSrcRect := ui.textureIndex[item.Rune][0]
 		if item == ui.draggedItem {
-			itemSize := int32(ItemSizeRatio * float32(ui.winWidth))
 			ui.renderer.Copy(ui.textureAtlas, &itemSrcRect, &sdl.Rect{int32(ui.currentMouseState.pos.X), int32(ui.currentMouseState.pos.Y), itemSize, itemSize})
 		} else {
 			ui.renderer.Copy(ui.textureAtlas, &itemSrcRect, ui.getInventoryItemRect(i))
@@ -34,23 +37,33 @@ func (ui *ui) DrawInventory(level *game.Level) {
 	}
 }
 
-func (ui *ui) getHelmetSlotRect() *sdl.Rect {
-	invRect := ui.getInventoryRect()
-	slotSize := int32(ItemSizeRatio * float32(ui.winWidth) * 1.05)
-
-	r := sdl.Rect{(invRect.X*2+invRect.W)/2 - slotSize/2, invRect.Y, slotSize, slotSize}
-	return &r
+// equipment slots drawn around the player, x and y are where the slot's center sits
+// as a share of the inventory's width and height
+var equipSlots = []struct {
+	x, y float32
+	slot game.Slot
+	item func(c *game.Character) *game.Item
+}{
+	{0.5, 0.05, game.HeadSlot, func(c *game.Character) *game.Item { return c.Helmet }},
+	{0.5, 0.15, game.NeckSlot, func(c *game.Character) *game.Item { return c.Amulet }},
+	{0.28, 0.28, game.WeaponSlot, func(c *game.Character) *game.Item { return c.Weapon }},
+	{0.5, 0.28, game.BodySlot, func(c *game.Character) *game.Item { return c.Armor }},
+	{0.72, 0.28, game.ShieldSlot, func(c *game.Character) *game.Item { return c.Shield }},
+	{0.28, 0.42, game.RingSlot, func(c *game.Character) *game.Item { return c.Ring1 }},
+	{0.72, 0.42, game.RingSlot, func(c *game.Character) *game.Item { return c.Ring2 }},
+	{0.5, 0.52, game.FeetSlot, func(c *game.Character) *game.Item { return c.Boots }},
 }
 
-func (ui *ui) getWeaponSlotRect() *sdl.Rect {
+func (ui *ui) getEquipSlotRect(i int) *sdl.Rect {
 	invRect := ui.getInventoryRect()
 	slotSize := int32(ItemSizeRatio * float32(ui.winWidth) * 1.05)
-	yoffset := int32(float32(invRect.H) * 0.23)
-	xoffset := int32(float32(invRect.W) * 0.23)
-
-	r := sdl.Rect{invRect.X + xoffset, invRect.Y + yoffset, slotSize, slotSize}
-	return &r
+	x := invRect.X + int32(float32(invRect.W)*equipSlots[i].x) - slotSize/2
+	y := invRect.Y + int32(float32(invRect.H)*equipSlots[i].y) - slotSize/2
+	if y < invRect.Y {
+		y = invRect.Y
+	}
 
+	return &sdl.Rect{x, y, slotSize, slotSize}
 }
 
 func (ui *ui) getInventoryRect() *sdl.Rect {
@@ -69,21 +82,54 @@ func (ui *ui) getInventoryItemRect(i int) *sdl.Rect {
 	return &sdl.Rect{invRect.X + int32(i)*itemSize, invRect.Y + invRect.H - itemSize, itemSize, itemSize}
 }
 
-func (ui *ui) CheckEquippedItem() *game.Item {
+// the dragged item when it's let go over a slot it fits, and for a ring which hand that
+// slot is, counting the ring slots from 1
+func (ui *ui) CheckEquippedItem() (*game.Item, int) {
 	mousePos := ui.currentMouseState.pos
 
-	if ui.draggedItem.Typ == game.Weapon {
-		r := ui.getWeaponSlotRect()
-		if r.HasIntersection(&sdl.Rect{int32(mousePos.X), int32(mousePos.Y), 1, 1}) {
-			return ui.draggedItem
+	rings := 0
+	for i, slot := range equipSlots {
+		ring := 0
+		if slot.slot == game.RingSlot {
+			rings++
+			ring = rings
+		}
+		if slot.slot != ui.draggedItem.Slot {
+			continue
 		}
-	} else if ui.draggedItem.Typ == game.Helmet {
-		r := ui.getHelmetSlotRect()
+		r := ui.getEquipSlotRect(i)
 		if r.HasIntersection(&sdl.Rect{int32(mousePos.X), int32(mousePos.Y), 1, 1}) {
-			return ui.draggedItem
+			return ui.draggedItem, ring
 		}
 	}
-	return nil
+	return nil, 0
+}
+
+// an equipped item let go over the bag, anywhere in the inventory that isn't a slot, comes off
+func (ui *ui) CheckUnequippedItem() *game.Item {
+	invRect := ui.getInventoryRect()
+	mouse := &sdl.Rect{int32(ui.currentMouseState.pos.X), int32(ui.currentMouseState.pos.Y), 1, 1}
+	if !invRect.HasIntersection(mouse) {
+		return nil
+	}
+	for i := range equipSlots {
+		if ui.getEquipSlotRect(i).HasIntersection(mouse) {
+			return nil
+		}
+	}
+	return ui.draggedItem
+}
+
+func (ui *ui) isEquipped(level *game.Level, item *game.Item) bool {
+	if item == nil {
+		return false
+	}
+	for _, slot := range equipSlots {
+		if slot.item(&level.Player.Character) == item {
+			return true
+		}
+	}
+	return false
 }
 
 func (ui *ui) CheckDroppedItem() *game.Item {
@@ -95,14 +141,30 @@ func (ui *ui) CheckDroppedItem() *game.Item {
 	return ui.draggedItem
 }
 
+// the bag item or equipped item under the mouse when the button goes down, it becomes the dragged item
 func (ui *ui) CheckInventoryItems(level *game.Level) *game.Item {
-	if ui.currentMouseState.leftButton {
-		mousePos := ui.currentMouseState.pos
-		for i, item := range level.Player.Items {
-			itemRect := ui.getInventoryItemRect(i)
-			if itemRect.HasIntersection(&sdl.Rect{int32(mousePos.X), int32(mousePos.Y), 1, 1}) {
-				return item
-			}
+	if !ui.currentMouseState.leftButton {
+		return nil
+	}
+	item := ui.inventoryItemAt(level, ui.currentMouseState.pos)
+	if item != nil {
+		return item
+	}
+	mouse := &sdl.Rect{int32(ui.currentMouseState.pos.X), int32(ui.currentMouseState.pos.Y), 1, 1}
+	for i, slot := range equipSlots {
+		if ui.getEquipSlotRect(i).HasIntersection(mouse) {
+			return slot.item(&level.Player.Character)
+		}
+	}
+	return nil
+}
+
+// the bag item drawn under the given window position, nil if there isn't one
+func (ui *ui) inventoryItemAt(level *game.Level, pos game.Pos) *game.Item {
+	for i, item := range level.Player.Items {
+		itemRect := ui.getInventoryItemRect(i)
+		if itemRect.HasIntersection(&sdl.Rect{int32(pos.X), int32(pos.Y), 1, 1}) {
+			return item
 		}
 	}
 	return nil
@@ -122,15 +184,3 @@ func (ui *ui) CheckGroundItems(level *game.Level) *game.Item {
 	}
 	return nil
 }
-
-// Game Over - You Have Died screen
-/*func (ui *ui) DrawGO() *sdl.Rect {
-	invWidth := int32(float32(ui.winWidth) * 0.4)
-	invHeight := int32(float32(ui.winHeight) * 0.75)
-	offsetX := (int32(ui.winWidth) - invWidth) / 2
-	offsetY := (int32(ui.winHeight) - invHeight) / 2
-
-	return &sdl.Rect{offsetX, offsetY, invWidth, invHeight}
-}*/
-
-// inspired by Jack Mott on Youtube's GamewithGo series
@@ -2,12 +2,15 @@ package ui2d
 
 import (
 	"bufio"
+	"embed"
 	"fmt"
 	"image/png"
 	"math/rand"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"unicode/utf8"
 	"unsafe"
 
 	"github.com/gorillana/rpg/game"
@@ -18,6 +21,44 @@ import (
 
 const ItemSizeRatio = 0.033
 
+// the art, font and sounds are built into the binary so it runs from any directory
+//
+//go:embed assets
+var assets embed.FS
+
+// sdl keeps reading fonts and music from the memory it's given while they play, so
+// everything handed to it is held on to here for as long as the program runs. Only the
+// main thread loads assets
+var assetData [][]byte
+
+// openAsset is an embedded asset for the sdl loaders that read from memory
+func openAsset(name string) (*sdl.RWops, error) {
+	data, err := assets.ReadFile("assets/" + name)
+	if err != nil {
+		return nil, err
+	}
+	assetData = append(assetData, data)
+	return sdl.RWFromMem(data)
+}
+
+// loadFont is the gothic font at size
+func loadFont(size int) (*ttf.Font, error) {
+	rw, err := openAsset("gothic.ttf")
+	if err != nil {
+		return nil, err
+	}
+	return ttf.OpenFontRW(rw, 1, size)
+}
+
+// loadSound is a sound effect from the assets
+func loadSound(name string) (*mix.Chunk, error) {
+	rw, err := openAsset(name)
+	if err != nil {
+		return nil, err
+	}
+	return mix.LoadWAVRW(rw, true)
+}
+
 type mouseState struct {
 	leftButton  bool
 	rightButton bool
@@ -38,6 +79,7 @@ func getMouseState() *mouseState {
 
 type sounds struct {
 	openingDoors []*mix.Chunk
+	closingDoors []*mix.Chunk
 	footsteps    []*mix.Chunk
 }
 
@@ -52,11 +94,14 @@ type uiState int
 const (
 	UIMain uiState = iota
 	UIInventory
+	UIDead
 )
 
 type ui struct {
 	state       uiState
 	draggedItem *game.Item
+	// C was pressed next to more than one open door, the next arrow picks which
+	closingDoor bool
 
 	sounds    sounds
 	winWidth  int
@@ -73,14 +118,23 @@ type ui struct {
 	centerY int
 	r       *rand.Rand
 
-	levelChan chan *game.Level
+	// the game's side of this window, what it follows and the frames it gets
+	view      *game.Window
 	inputChan chan *game.Input
+	// SDL hands every window's events to whoever polls, Run picks out ours by this
+	windowID uint32
+	// the last frame and its level, drawn until the next one comes
+	frame *game.Frame
+	level *game.Level
+	// W was pressed for another window on this view
+	openView *game.Window
 
 	fontSmall  *ttf.Font
 	fontMedium *ttf.Font
 	fontLarge  *ttf.Font
 
 	eventBackground           *sdl.Texture
+	deathBackground           *sdl.Texture
 	groundInventoryBackground *sdl.Texture
 	slotBackground            *sdl.Texture
 
@@ -92,7 +146,12 @@ type ui struct {
 	prevMouseState    *mouseState
 }
 
-func NewUI(inputChan chan *game.Input, levelChan chan *game.Level) *ui {
+func NewUI(inputChan chan *game.Input, view *game.Window) (*ui, error) {
+
+	initOnce.Do(initSDL)
+	if initErr != nil {
+		return nil, initErr
+	}
 
 	ui := &ui{}
 	ui.state = UIMain
@@ -101,7 +160,7 @@ func NewUI(inputChan chan *game.Input, levelChan chan *game.Level) *ui {
 	ui.str2TexMedium = make(map[string]*sdl.Texture)
 	ui.str2TexSmall = make(map[string]*sdl.Texture)
 	ui.inputChan = inputChan
-	ui.levelChan = levelChan
+	ui.view = view
 	ui.r = rand.New(rand.NewSource(1))
 
 	ui.winHeight = 720
@@ -110,22 +169,33 @@ func NewUI(inputChan chan *game.Input, levelChan chan *game.Level) *ui {
 	window, err := sdl.CreateWindow("RPG!!", 200, 200,
 		int32(ui.winWidth), int32(ui.winHeight), sdl.WINDOW_SHOWN)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	ui.window = window
+	ui.windowID, err = window.GetID()
+	if err != nil {
+		return nil, err
+	}
 
 	// used to draw textures // accelerated means gpu usage
 	ui.renderer, err = sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	// bilinear filtering, will help graphics look smoother
 	//sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "1")
 
-	ui.textureAtlas = ui.imgFileToTexure("ui2d/assets/tiles.png")
-	ui.loadTextureIndex()
+	ui.textureAtlas, err = ui.imgFileToTexure("tiles.png")
+	if err != nil {
+		return nil, err
+	}
+	err = ui.loadTextureIndex()
+	if err != nil {
+		return nil, err
+	}
 
+	ui.prevMouseState = getMouseState()
 	ui.keyboardState = sdl.GetKeyboardState()
 	ui.prevKeyboardState = make([]uint8, len(ui.keyboardState))
 	for i, v := range ui.keyboardState {
@@ -136,23 +206,26 @@ func NewUI(inputChan chan *game.Input, levelChan chan *game.Level) *ui {
 	ui.centerY = -1
 
 	// made the font a percentage of the window to keep it consistent with different screen sizes
-	ui.fontSmall, err = ttf.OpenFont(("ui2d/assets/gothic.ttf"), int(float64(ui.winWidth)*.012))
+	ui.fontSmall, err = loadFont(int(float64(ui.winWidth) * .012))
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	ui.fontMedium, err = ttf.OpenFont(("ui2d/assets/gothic.ttf"), 32)
+	ui.fontMedium, err = loadFont(32)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	ui.fontLarge, err = ttf.OpenFont(("ui2d/assets/gothic.ttf"), 64)
+	ui.fontLarge, err = loadFont(64)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	//renders event background
 	ui.eventBackground = ui.GetSinglePixelTex(sdl.Color{0, 0, 0, 128})
 	ui.eventBackground.SetBlendMode(sdl.BLENDMODE_BLEND)
 
+	ui.deathBackground = ui.GetSinglePixelTex(sdl.Color{60, 0, 0, 200})
+	ui.deathBackground.SetBlendMode(sdl.BLENDMODE_BLEND)
+
 	ui.groundInventoryBackground = ui.GetSinglePixelTex(sdl.Color{25, 44, 54, 128})
 	ui.groundInventoryBackground.SetBlendMode(sdl.BLENDMODE_BLEND)
 
@@ -161,38 +234,48 @@ func NewUI(inputChan chan *game.Input, levelChan chan *game.Level) *ui {
 
 	err = mix.OpenAudio(22050, mix.DEFAULT_FORMAT, 2, 4096)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	mus, err := mix.LoadMUS("ui2d/assets/ambient.ogg")
+	rw, err := openAsset("ambient.ogg")
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	mus, err := mix.LoadMUSRW(rw, 1)
+	if err != nil {
+		return nil, err
 	}
 
 	mus.Play(-1)
 
-	footstepBase := "ui2d/assets/footstep0"
+	footstepBase := "footstep0"
 	for i := 0; i < 10; i++ {
 		footstepFile := footstepBase + strconv.Itoa(i) + ".ogg"
-		footstepSound, err := mix.LoadWAV(footstepFile)
+		footstepSound, err := loadSound(footstepFile)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 		ui.sounds.footsteps = append(ui.sounds.footsteps, footstepSound)
 	}
 
-	doorOpen1, err := mix.LoadWAV("ui2d/assets/doorOpen_1.ogg")
+	doorOpen1, err := loadSound("doorOpen_1.ogg")
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	ui.sounds.openingDoors = append(ui.sounds.openingDoors, doorOpen1)
 
-	doorOpen2, err := mix.LoadWAV("ui2d/assets/doorOpen_2.ogg")
+	doorOpen2, err := loadSound("doorOpen_2.ogg")
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	ui.sounds.openingDoors = append(ui.sounds.openingDoors, doorOpen2)
 
-	return ui
+	doorClose1, err := loadSound("doorClose_1.ogg")
+	if err != nil {
+		return nil, err
+	}
+	ui.sounds.closingDoors = append(ui.sounds.closingDoors, doorClose1)
+
+	return ui, nil
 }
 
 type FontSize int
@@ -251,31 +334,41 @@ func (ui *ui) stringToTexture(s string, color sdl.Color, size FontSize) *sdl.Tex
 }
 
 // receiver ui type from ui struct above
-func (ui *ui) loadTextureIndex() {
+func (ui *ui) loadTextureIndex() error {
+	const indexFile = "atlas-index.txt"
 	ui.textureIndex = make(map[rune][]sdl.Rect)
-	infile, err := os.Open("ui2d/assets/atlas-index.txt")
+	infile, err := assets.Open("assets/" + indexFile)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer infile.Close()
 	scanner := bufio.NewScanner(infile)
 
+	lineNum := 0
 	for scanner.Scan() {
+		lineNum++
 		line := scanner.Text()
 		line = strings.TrimSpace(line)
-		tileRune := rune(line[0])
-		xy := line[1:]
+		if line == "" {
+			continue
+		}
+		tileRune, size := utf8.DecodeRuneInString(line)
+		xy := line[size:]
 		splitXYC := strings.Split(xy, ",")
+		if len(splitXYC) != 3 {
+			return fmt.Errorf("%s:%d: expected rune x,y,variations but got %q", indexFile, lineNum, line)
+		}
 		x, err := strconv.ParseInt(strings.TrimSpace(splitXYC[0]), 10, 64)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("%s:%d: bad x: %w", indexFile, lineNum, err)
 		}
 		y, err := strconv.ParseInt(strings.TrimSpace(splitXYC[1]), 10, 64)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("%s:%d: bad y: %w", indexFile, lineNum, err)
 		}
 		variationCount, err := strconv.ParseInt(strings.TrimSpace(splitXYC[2]), 10, 64)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("%s:%d: bad variation count: %w", indexFile, lineNum, err)
 		}
 
 		var rects []sdl.Rect
@@ -289,21 +382,83 @@ func (ui *ui) loadTextureIndex() {
 				y++
 			}
 		}
-		fmt.Println("rectLen:", len(rects))
 		ui.textureIndex[tileRune] = rects
 	}
+	return scanner.Err()
 }
 
-func (ui *ui) imgFileToTexure(filename string) *sdl.Texture {
-	infile, err := os.Open(filename)
+// checkSprites is an error naming the first thing on level the atlas has no sprite for.
+// Monsters and items come from whatever files the game loaded, so they're looked at in
+// every frame along with what everyone carries. Tiles only when the level is new, they
+// don't turn into anything else
+func (ui *ui) checkSprites(level *game.Level, newLevel bool) error {
+	check := func(r rune, what string) error {
+		if len(ui.textureIndex[r]) == 0 {
+			return fmt.Errorf("atlas-index.txt has no sprite %q for %s", r, what)
+		}
+		return nil
+	}
+	checkCharacter := func(c *game.Character) error {
+		err := check(c.Rune, c.Name)
+		if err != nil {
+			return err
+		}
+		for _, item := range append(c.Items, c.Equipped()...) {
+			err = check(item.Rune, "item "+item.Name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if newLevel {
+		for _, row := range level.Map {
+			for _, tile := range row {
+				if tile.Rune != game.Blank {
+					if err := check(tile.Rune, "a tile"); err != nil {
+						return err
+					}
+				}
+				if tile.OverlayRune != game.Blank {
+					if err := check(tile.OverlayRune, "a tile"); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	err := checkCharacter(&level.Player.Character)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	for _, m := range level.Monsters {
+		err = checkCharacter(&m.Character)
+		if err != nil {
+			return err
+		}
+	}
+	for _, items := range level.Items {
+		for _, item := range items {
+			err = check(item.Rune, "item "+item.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ui *ui) imgFileToTexure(filename string) (*sdl.Texture, error) {
+	infile, err := assets.Open("assets/" + filename)
+	if err != nil {
+		return nil, err
 	}
 	defer infile.Close()
 
 	img, err := png.Decode(infile)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("%s: %w", filename, err)
 	}
 
 	w := img.Bounds().Max.X
@@ -327,63 +482,86 @@ func (ui *ui) imgFileToTexure(filename string) *sdl.Texture {
 
 	tex, err := ui.renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STATIC, int32(w), int32(h))
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	tex.Update(nil, unsafe.Pointer(&pixels[0]), w*4)
 
 	// lets transparency work
 	err = tex.SetBlendMode(sdl.BLENDMODE_BLEND)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	return tex
+	return tex, nil
 
 }
 
-func init() {
+var (
+	initOnce sync.Once
+	initErr  error
+)
+
+// sdl is initialized on the first NewUI instead of in init() so the binary
+// can still start a text frontend on machines without a display
+func initSDL() {
 	// Added after Ep06 to address macosx issues
-	err := sdl.Init(sdl.INIT_EVERYTHING)
-	if err != nil {
-		panic(err)
+	initErr = sdl.Init(sdl.INIT_EVERYTHING)
+	if initErr != nil {
+		return
 	}
 
-	err = ttf.Init()
-	if err != nil {
-		panic(err)
+	initErr = ttf.Init()
+	if initErr != nil {
+		return
 	}
 
-	err = mix.Init(mix.INIT_OGG)
-	if err != nil {
-		panic(err)
-	}
+	initErr = mix.Init(mix.INIT_OGG)
 }
 
-func (ui *ui) Draw(level *game.Level) {
+// remembered tiles are drawn this dark, visible ones go from darkestShade with no light up to full
+const (
+	rememberedShade = 80
+	darkestShade    = 112
+)
+
+// sets the atlas color for drawing something at pos by how lit it is. Torchlight
+// and glowing monsters are a little warmer than the level's own light
+func (ui *ui) shadeByLight(level *game.Level, pos game.Pos) {
+	shade := darkestShade + (255-darkestShade)*level.LightAt(pos)/game.MaxLight
+	warmth := 48 * level.Map[pos.Y][pos.X].Light / game.MaxLight
+	ui.textureAtlas.SetColorMod(uint8(shade), uint8(shade-warmth/2), uint8(shade-warmth))
+}
+
+// where the map's top left corner is drawn, multiplying by 32 keeps the center in the middle of the window
+func (ui *ui) mapOffset() (int32, int32) {
+	return int32((ui.winWidth / 2) - ui.centerX*32), int32((ui.winHeight / 2) - ui.centerY*32)
+}
+
+func (ui *ui) Draw(frame *game.Frame) {
+	level := frame.Level
+	follow := frame.Center
 	// keep track of center
 	if ui.centerX == -1 && ui.centerY == -1 {
-		ui.centerX = level.Player.X
-		ui.centerY = level.Player.Y
+		ui.centerX = follow.X
+		ui.centerY = follow.Y
 	}
 
-	// recenter screen around player sprite
+	// recenter screen around whatever the window follows
 	limit := 5
-	if level.Player.X > ui.centerX+limit {
-		diff := level.Player.X - (ui.centerX + limit)
+	if follow.X > ui.centerX+limit {
+		diff := follow.X - (ui.centerX + limit)
 		ui.centerX += diff
-	} else if level.Player.X < ui.centerX-limit {
-		diff := (ui.centerX - limit) - level.Player.X
+	} else if follow.X < ui.centerX-limit {
+		diff := (ui.centerX - limit) - follow.X
 		ui.centerX -= diff
-	} else if level.Player.Y > ui.centerY+limit {
-		diff := level.Player.Y - (ui.centerY + limit)
+	} else if follow.Y > ui.centerY+limit {
+		diff := follow.Y - (ui.centerY + limit)
 		ui.centerY += diff
-	} else if level.Player.Y < ui.centerY-limit {
-		diff := (ui.centerY - limit) - level.Player.Y
+	} else if follow.Y < ui.centerY-limit {
+		diff := (ui.centerY - limit) - follow.Y
 		ui.centerY -= diff
 	}
 
-	// multiplying by 32 keeps the player in center of screen
-	offsetX := int32((ui.winWidth / 2) - ui.centerX*32)
-	offsetY := int32((ui.winHeight / 2) - ui.centerY*32)
+	offsetX, offsetY := ui.mapOffset()
 
 	// clear before re-drawing the tiles/ floor tiles
 	ui.renderer.Clear()
@@ -395,16 +573,16 @@ func (ui *ui) Draw(level *game.Level) {
 			if tile.Rune != game.Blank {
 				srcRects := ui.textureIndex[tile.Rune]
 				srcRect := srcRects[ui.r.Intn(len(srcRects))]
-				if tile.Visible || tile.Seen {
+				if tile.Visible || tile.Seen || frame.Reveal {
 					dstRect := sdl.Rect{X: int32(x*32) + offsetX, Y: int32(y*32) + offsetY, W: 32, H: 32}
 					pos := game.Pos{x, y}
 					if level.Debug[pos] {
 						// any calls to copy will mult set color ontop of the copy call
 						ui.textureAtlas.SetColorMod(128, 0, 0)
-					} else if tile.Seen && !tile.Visible {
-						ui.textureAtlas.SetColorMod(128, 128, 128)
+					} else if !tile.Visible && !frame.Reveal {
+						ui.textureAtlas.SetColorMod(rememberedShade, rememberedShade, rememberedShade)
 					} else {
-						ui.textureAtlas.SetColorMod(255, 255, 255)
+						ui.shadeByLight(level, pos)
 					}
 					ui.renderer.Copy(ui.textureAtlas, &srcRect, &dstRect)
 
@@ -417,10 +595,10 @@ func (ui *ui) Draw(level *game.Level) {
 			}
 		}
 	}
-	ui.textureAtlas.SetColorMod(255, 255, 255)
 	// draws monsters
 	for pos, monster := range level.Monsters {
-		if level.Map[pos.Y][pos.X].Visible {
+		if level.Map[pos.Y][pos.X].Visible || frame.Reveal {
+			ui.shadeByLight(level, pos)
 			monsterSrcRect := ui.textureIndex[(monster.Rune)][0]
 			ui.renderer.Copy(ui.textureAtlas, &monsterSrcRect, &sdl.Rect{int32(pos.X)*32 + offsetX, int32(pos.Y)*32 + offsetY, 32, 32})
 		}
@@ -428,7 +606,8 @@ func (ui *ui) Draw(level *game.Level) {
 
 	// renders items
 	for pos, items := range level.Items {
-		if level.Map[pos.Y][pos.X].Visible {
+		if level.Map[pos.Y][pos.X].Visible || frame.Reveal {
+			ui.shadeByLight(level, pos)
 			for _, item := range items {
 				itemSrcRect := ui.textureIndex[item.Rune][0]
 				ui.renderer.Copy(ui.textureAtlas, &itemSrcRect, &sdl.Rect{int32(pos.X)*32 + offsetX, int32(pos.Y)*32 + offsetY, 32, 32})
@@ -436,9 +615,37 @@ func (ui *ui) Draw(level *game.Level) {
 		}
 	}
 
-	// draws the player
-	playerSrcRect := ui.textureIndex[level.Player.Rune][0]
-	ui.renderer.Copy(ui.textureAtlas, &playerSrcRect, &sdl.Rect{X: (int32(level.Player.X)*32 + offsetX), Y: (int32(level.Player.Y)*32 + offsetY), W: 32, H: 32})
+	// draws the player, when they're on this level
+	if frame.HasPlayer {
+		ui.shadeByLight(level, level.Player.Pos)
+		playerSrcRect := ui.textureIndex[level.Player.Rune][0]
+		ui.renderer.Copy(ui.textureAtlas, &playerSrcRect, &sdl.Rect{X: (int32(level.Player.X)*32 + offsetX), Y: (int32(level.Player.Y)*32 + offsetY), W: 32, H: 32})
+	}
+	ui.textureAtlas.SetColorMod(255, 255, 255)
+
+	// what the last turn's swings did, stacked above whoever took them
+	stacked := make(map[game.Pos]int32)
+	for _, event := range level.TurnEvents {
+		var text string
+		var color sdl.Color
+		switch event.Kind {
+		case game.Miss:
+			text, color = "miss", sdl.Color{200, 200, 200, 0}
+		case game.Crit:
+			text, color = "crit "+strconv.Itoa(event.Amount), sdl.Color{255, 220, 0, 0}
+		case game.Hit:
+			text, color = strconv.Itoa(event.Amount), sdl.Color{255, 0, 0, 0}
+		case game.Burned:
+			text, color = "burn "+strconv.Itoa(event.Amount), sdl.Color{255, 120, 0, 0}
+		default:
+			continue
+		}
+		tex := ui.stringToTexture(text, color, FontSmall)
+		_, _, w, h, _ := tex.Query()
+		y := int32(event.Pos.Y)*32 + offsetY - h*(stacked[event.Pos]+1)
+		ui.renderer.Copy(tex, nil, &sdl.Rect{int32(event.Pos.X)*32 + offsetX + 16 - w/2, y, w, h})
+		stacked[event.Pos]++
+	}
 
 	// Event UI Begin
 	textStart := int32(float64(ui.winHeight) * .74)
@@ -472,6 +679,9 @@ func (ui *ui) Draw(level *game.Level) {
 	// Event UI End
 
 	// Inventory UI
+	if !frame.HasPlayer {
+		return
+	}
 	groundInvStart := int32(float64(ui.winWidth) * .9)
 	groundInvWidth := int32(ui.winWidth) - groundInvStart
 	groundInvHeight := int32(ItemSizeRatio * float32(ui.winWidth))
@@ -488,6 +698,19 @@ func (ui *ui) Draw(level *game.Level) {
 
 }
 
+// Game Over - You Have Died screen
+func (ui *ui) DrawDeathScreen() {
+	ui.renderer.Copy(ui.deathBackground, nil, nil)
+
+	tex := ui.stringToTexture("You have died", sdl.Color{255, 0, 0, 0}, FontLarge)
+	_, _, w, h, _ := tex.Query()
+	ui.renderer.Copy(tex, nil, &sdl.Rect{int32(ui.winWidth)/2 - w/2, int32(ui.winHeight)/2 - h, w, h})
+
+	tex = ui.stringToTexture("Press R to restart", sdl.Color{255, 255, 255, 0}, FontMedium)
+	_, _, w2, h2, _ := tex.Query()
+	ui.renderer.Copy(tex, nil, &sdl.Rect{int32(ui.winWidth)/2 - w2/2, int32(ui.winHeight)/2 + h2/2, w2, h2})
+}
+
 func (ui *ui) getGroundItemRect(i int) *sdl.Rect {
 	itemSize := int32(ItemSizeRatio * float32(ui.winWidth))
 	return &sdl.Rect{int32(ui.winWidth) - itemSize - int32(i)*itemSize, int32(ui.winHeight) - itemSize, itemSize, itemSize}
@@ -498,6 +721,48 @@ func (ui *ui) keyDownOnce(key uint8) bool {
 }
 
 // Check for key pressed then release
+// an arrow pressed this frame as a step, for actions that need a direction
+func (ui *ui) directionKey() (game.Pos, bool) {
+	for _, k := range directionKeys {
+		// the vi letters are for other things while the inventory is open
+		if k.vi && ui.state == UIInventory {
+			continue
+		}
+		if ui.keyDownOnce(k.key) {
+			return k.dir, true
+		}
+	}
+	return game.Pos{}, false
+}
+
+// arrows, the numpad and vi keys all move, the diagonals only do anything when the game allows them
+var directionKeys = []struct {
+	key uint8
+	dir game.Pos
+	vi  bool
+}{
+	{sdl.SCANCODE_UP, game.Pos{0, -1}, false},
+	{sdl.SCANCODE_DOWN, game.Pos{0, 1}, false},
+	{sdl.SCANCODE_LEFT, game.Pos{-1, 0}, false},
+	{sdl.SCANCODE_RIGHT, game.Pos{1, 0}, false},
+	{sdl.SCANCODE_KP_8, game.Pos{0, -1}, false},
+	{sdl.SCANCODE_KP_2, game.Pos{0, 1}, false},
+	{sdl.SCANCODE_KP_4, game.Pos{-1, 0}, false},
+	{sdl.SCANCODE_KP_6, game.Pos{1, 0}, false},
+	{sdl.SCANCODE_KP_7, game.Pos{-1, -1}, false},
+	{sdl.SCANCODE_KP_9, game.Pos{1, -1}, false},
+	{sdl.SCANCODE_KP_1, game.Pos{-1, 1}, false},
+	{sdl.SCANCODE_KP_3, game.Pos{1, 1}, false},
+	{sdl.SCANCODE_K, game.Pos{0, -1}, true},
+	{sdl.SCANCODE_J, game.Pos{0, 1}, true},
+	{sdl.SCANCODE_H, game.Pos{-1, 0}, true},
+	{sdl.SCANCODE_L, game.Pos{1, 0}, true},
+	{sdl.SCANCODE_Y, game.Pos{-1, -1}, true},
+	{sdl.SCANCODE_U, game.Pos{1, -1}, true},
+	{sdl.SCANCODE_B, game.Pos{-1, 1}, true},
+	{sdl.SCANCODE_N, game.Pos{1, 1}, true},
+}
+
 func (ui *ui) keyPressed(key uint8) bool {
 	return ui.keyboardState[key] == 0 && ui.prevKeyboardState[key] == 1
 }
@@ -516,112 +781,267 @@ func (ui *ui) GetSinglePixelTex(color sdl.Color) *sdl.Texture {
 	return tex
 }
 
-func (ui *ui) Run() {
-	var newLevel *game.Level
-	ui.prevMouseState = getMouseState()
+// Window is a window to open, the game's side of it and where what the player does in it goes
+type Window struct {
+	Input chan *game.Input
+	View  *game.Window
+}
 
-	for {
+// Run opens windows and draws them until every one has been closed or the player quits.
+// SDL only works from the main thread and hands the events for every window to whoever
+// polls, so Run has to be called on the main thread, where it polls once a frame and
+// passes each window its own events
+func Run(windows ...Window) error {
+	var uis []*ui
+	for _, w := range windows {
+		ui, err := NewUI(w.Input, w.View)
+		if err != nil {
+			return err
+		}
+		uis = append(uis, ui)
+	}
+
+	for len(uis) > 0 {
+		events := make(map[uint32][]sdl.Event)
 		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
 			switch e := event.(type) {
 			case *sdl.QuitEvent:
-				ui.inputChan <- &game.Input{Typ: game.QuitGame}
+				quit(uis)
+				return nil
+			case *sdl.MouseButtonEvent:
+				events[e.WindowID] = append(events[e.WindowID], e)
 			case *sdl.WindowEvent:
-				if e.Event == sdl.WINDOWEVENT_CLOSE {
-					ui.inputChan <- &game.Input{Typ: game.CloseWindow, LevelChannel: ui.levelChan}
+				events[e.WindowID] = append(events[e.WindowID], e)
+			}
+		}
+
+		var open, opened []*ui
+		for _, ui := range uis {
+			ok, err := ui.update(events[ui.windowID])
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			open = append(open, ui)
+			if ui.openView != nil {
+				if other := ui.openWindow(); other != nil {
+					opened = append(opened, other)
 				}
 			}
 		}
-		ui.currentMouseState = getMouseState()
+		uis = append(open, opened...)
+		sdl.Delay(10)
+	}
+	return nil
+}
+
+// quit tells the game behind every window to quit, once for each game
+func quit(uis []*ui) {
+	told := make(map[chan *game.Input]bool)
+	for _, ui := range uis {
+		if !told[ui.inputChan] {
+			ui.inputChan <- &game.Input{Typ: game.QuitGame}
+			told[ui.inputChan] = true
+		}
+	}
+}
 
-		// Suspect quick keypresses sometimes cause channel gridlock
-		var ok bool
-		select {
-		case newLevel, ok = <-ui.levelChan:
-			if ok {
-				switch newLevel.LastEvent {
+// openWindow opens the window W asked for on the same view as this one, nil when it couldn't
+func (ui *ui) openWindow() *ui {
+	view := ui.openView
+	ui.openView = nil
+	ui.inputChan <- &game.Input{Typ: game.OpenWindow, Window: view}
+	other, err := NewUI(ui.inputChan, view)
+	if err != nil {
+		// the windows already open carry on, so this isn't worth stopping the game for
+		fmt.Fprintln(os.Stderr, "Couldn't open window:", err)
+		ui.inputChan <- &game.Input{Typ: game.CloseWindow, Window: view}
+		return nil
+	}
+	return other
+}
+
+// update is a frame of this window: it handles the events Run picked out for it, takes
+// the game's next frame if there is one, draws and sends what the player did. False once
+// the game has let go of the window, an error when the frame has something it can't draw
+func (ui *ui) update(events []sdl.Event) (bool, error) {
+	var usedItem *game.Item
+	for _, event := range events {
+		switch e := event.(type) {
+		case *sdl.MouseButtonEvent:
+			// double clicking something in the bag uses it
+			if ui.state == UIInventory && ui.level != nil && e.Button == sdl.BUTTON_LEFT && e.State == sdl.PRESSED && e.Clicks == 2 {
+				usedItem = ui.inventoryItemAt(ui.level, game.Pos{int(e.X), int(e.Y)})
+			}
+		case *sdl.WindowEvent:
+			if e.Event == sdl.WINDOWEVENT_CLOSE {
+				ui.inputChan <- &game.Input{Typ: game.CloseWindow, Window: ui.view}
+			}
+		}
+	}
+	ui.currentMouseState = getMouseState()
+
+	select {
+	case next, ok := <-ui.view.Frames():
+		if !ok {
+			// the game has let go of this window
+			ui.window.Destroy()
+			return false, nil
+		}
+		err := ui.checkSprites(next.Level, next.Level != ui.level)
+		if err != nil {
+			return false, err
+		}
+		ui.frame, ui.level = next, next.Level
+		if next.HasPlayer {
+			for _, event := range next.Level.TurnEvents {
+				switch event.Kind {
 				case game.Move:
 					playRandomSound(ui.sounds.footsteps, 10)
 				case game.DoorOpen:
 					playRandomSound(ui.sounds.openingDoors, 32)
+				case game.DoorClose:
+					playRandomSound(ui.sounds.closingDoors, 32)
+				case game.Death:
+					ui.state = UIDead
 				default:
 					// add more sounds
 				}
 			}
-		default:
 		}
-		ui.Draw(newLevel)
-		var input game.Input
-		if ui.state == UIInventory {
-
-			// we have stopped dragging
-			if ui.draggedItem != nil && !ui.currentMouseState.leftButton && ui.prevMouseState.leftButton {
-
-				item := ui.CheckEquippedItem()
+	default:
+	}
+	if ui.frame == nil {
+		// nothing to draw until the game sends the first frame
+		return true, nil
+	}
+	frame, newLevel := ui.frame, ui.level
+	ui.Draw(frame)
+	var input game.Input
+	if ui.state == UIInventory {
+
+		// we have stopped dragging
+		if ui.isEquipped(newLevel, ui.draggedItem) && !ui.currentMouseState.leftButton && ui.prevMouseState.leftButton {
+			item := ui.CheckUnequippedItem()
+			if item != nil {
+				input.Typ = game.UnequipItem
+				input.Item = item
+			}
+			ui.draggedItem = nil
+		} else if ui.draggedItem != nil && !ui.currentMouseState.leftButton && ui.prevMouseState.leftButton {
+
+			item, ring := ui.CheckEquippedItem()
+			if item != nil {
+				input.Typ = game.EquipItem
+				input.Item = item
+				input.Ring = ring
+				ui.draggedItem = nil
+			}
+			if ui.draggedItem != nil {
+				item := ui.CheckDroppedItem()
 				if item != nil {
-					input.Typ = game.EquipItem
+					input.Typ = game.DropItem
 					input.Item = item
 					ui.draggedItem = nil
 				}
-				if ui.draggedItem != nil {
-					item := ui.CheckDroppedItem()
-					if item != nil {
-						input.Typ = game.DropItem
-						input.Item = item
-						ui.draggedItem = nil
-					}
-				}
-
 			}
 
-			if !ui.currentMouseState.leftButton || ui.draggedItem == nil {
-				ui.draggedItem = ui.CheckInventoryItems(newLevel)
+		}
+
+		if !ui.currentMouseState.leftButton || ui.draggedItem == nil {
+			ui.draggedItem = ui.CheckInventoryItems(newLevel)
+		}
+		ui.DrawInventory(newLevel)
+	}
+	if ui.state == UIDead {
+		ui.DrawDeathScreen()
+	}
+	if ui.closingDoor {
+		tex := ui.stringToTexture("Close which door? A direction picks, Esc cancels", sdl.Color{255, 255, 255, 0}, FontMedium)
+		_, _, w, h, _ := tex.Query()
+		ui.renderer.Copy(tex, nil, &sdl.Rect{int32(ui.winWidth)/2 - w/2, 10, w, h})
+	}
+	ui.renderer.Present()
+
+	item := ui.CheckGroundItems(newLevel)
+	if item != nil && frame.HasPlayer {
+		input.Typ = game.TakeItem
+		input.Item = item
+	}
+	if usedItem != nil {
+		input.Typ = game.UseItem
+		input.Item = usedItem
+		ui.draggedItem = nil
+	}
+
+	if sdl.GetKeyboardFocus() == ui.window || sdl.GetMouseFocus() == ui.window {
+
+		if ui.state == UIDead {
+			if ui.keyDownOnce(sdl.SCANCODE_R) {
+				input.Typ = game.Restart
+				ui.state = UIMain
+				ui.centerX = -1
+				ui.centerY = -1
+			} else if ui.keyDownOnce(sdl.SCANCODE_F9) {
+				input.Typ = game.LoadGame
+				ui.state = UIMain
 			}
-			ui.DrawInventory(newLevel)
-		}
-		ui.renderer.Present()
-
-		item := ui.CheckGroundItems(newLevel)
-		if item != nil {
-			input.Typ = game.TakeItem
-			input.Item = item
-		}
-
-		if sdl.GetKeyboardFocus() == ui.window || sdl.GetMouseFocus() == ui.window {
-
-			if ui.keyDownOnce(sdl.SCANCODE_UP) {
-				input.Typ = game.Up
-			} else if ui.keyDownOnce(sdl.SCANCODE_DOWN) {
-				input.Typ = game.Down
-			} else if ui.keyDownOnce(sdl.SCANCODE_LEFT) {
-				input.Typ = game.Left
-			} else if ui.keyDownOnce(sdl.SCANCODE_RIGHT) {
-				input.Typ = game.Right
-			} else if ui.keyDownOnce(sdl.SCANCODE_T) {
-				input.Typ = game.TakeAll
-			} else if ui.keyDownOnce(sdl.SCANCODE_I) {
-				fmt.Println("I")
-				if ui.state == UIMain {
-					ui.state = UIInventory
-				} else {
-					ui.state = UIMain
-				}
+		} else if ui.closingDoor {
+			dir, picked := ui.directionKey()
+			if picked {
+				input.Typ = game.ShutDoor
+				input.Pos = game.Pos{newLevel.Player.X + dir.X, newLevel.Player.Y + dir.Y}
+				ui.closingDoor = false
+			} else if ui.keyDownOnce(sdl.SCANCODE_ESCAPE) {
+				ui.closingDoor = false
 			}
-
-			for i, v := range ui.keyboardState {
-				ui.prevKeyboardState[i] = v
+		} else if ui.keyDownOnce(sdl.SCANCODE_C) {
+			// with only one door in reach there's nothing to ask
+			doors := newLevel.OpenDoorsNear(newLevel.Player.Pos)
+			if len(doors) == 1 {
+				input.Typ = game.ShutDoor
+				input.Pos = doors[0]
+			} else if len(doors) > 1 {
+				ui.closingDoor = true
 			}
-
-			if input.Typ != game.None {
-				ui.inputChan <- &input
+		} else if dir, picked := ui.directionKey(); picked {
+			input.Typ = game.MoveInput(dir)
+		} else if ui.keyDownOnce(sdl.SCANCODE_T) {
+			input.Typ = game.TakeAll
+		} else if ui.keyDownOnce(sdl.SCANCODE_F5) {
+			input.Typ = game.SaveGame
+		} else if ui.keyDownOnce(sdl.SCANCODE_F9) {
+			input.Typ = game.LoadGame
+		} else if ui.keyDownOnce(sdl.SCANCODE_W) {
+			// opened by Run once every window has had its turn
+			ui.openView = game.NewWindow(ui.view.View)
+		} else if ui.state == UIInventory && ui.keyDownOnce(sdl.SCANCODE_U) {
+			// U uses whatever the mouse is over
+			item := ui.inventoryItemAt(newLevel, ui.currentMouseState.pos)
+			if item != nil {
+				input.Typ = game.UseItem
+				input.Item = item
+			}
+		} else if ui.keyDownOnce(sdl.SCANCODE_I) {
+			if ui.state == UIMain {
+				ui.state = UIInventory
+			} else {
+				ui.state = UIMain
 			}
 		}
-		ui.prevMouseState = ui.currentMouseState
-		sdl.Delay(10)
-		/* if c.character.Hitpoints == 0{
-		// ui.DrawDeadScreen
-		 }*/
-	}
 
+		for i, v := range ui.keyboardState {
+			ui.prevKeyboardState[i] = v
+		}
+
+		if input.Typ != game.None {
+			ui.inputChan <- &input
+		}
+	}
+	ui.prevMouseState = ui.currentMouseState
+	return true, nil
 }
 
 // inspired by Jack Mott on Youtube's GamewithGo series
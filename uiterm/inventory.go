@@ -0,0 +1,91 @@
+package uiterm
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/gorillana/rpg/game"
+)
+
+func slotName(item *game.Item) string {
+	if item == nil {
+		return colorGrey + "(empty)" + ansiReset
+	}
+	return colorCyan + item.Name + ansiReset
+}
+
+func (ui *ui) drawInventory(b *bytes.Buffer, level *game.Level) {
+	p := level.Player
+	b.WriteString("\r\n" + ansiBold + "Inventory" + ansiReset + "\r\n")
+	b.WriteString("Helmet: " + slotName(p.Helmet) + "  Amulet: " + slotName(p.Amulet) + "  Armor: " + slotName(p.Armor) + "  Boots: " + slotName(p.Boots) + "\r\n")
+	b.WriteString("Weapon: " + slotName(p.Weapon) + "  Shield: " + slotName(p.Shield) + "  Rings: " + slotName(p.Ring1) + ", " + slotName(p.Ring2) + "\r\n")
+
+	if len(p.Items) == 0 {
+		b.WriteString(colorGrey + "your bag is empty" + ansiReset + "\r\n")
+	}
+	for i, item := range p.Items {
+		line := strconv.Itoa(i+1) + ") " + item.Name
+		if i == ui.selected {
+			line = ansiReverse + line + ansiReset
+		}
+		b.WriteString(line + "\r\n")
+	}
+	item := ui.selectedItem()
+	if item != nil && item.Description != "" {
+		b.WriteString(colorCyan + item.Description + ansiReset + "\r\n")
+	}
+	if ui.takingOff {
+		b.WriteString(ansiBold + "Take off which? 1-6: helmet, weapon, armor, boots, shield, amulet  7-8: rings, any other key cancels" + ansiReset + "\r\n")
+	} else {
+		b.WriteString(colorGrey + "1-9: select  e: equip  r: take off  u: use  d: drop  i/esc: close" + ansiReset + "\r\n")
+	}
+}
+
+func (ui *ui) selectedItem() *game.Item {
+	items := ui.level.Player.Items
+	if ui.selected < 0 || ui.selected >= len(items) {
+		return nil
+	}
+	return items[ui.selected]
+}
+
+func (ui *ui) handleInventoryKey(k key) *game.Input {
+	if ui.takingOff {
+		ui.takingOff = false
+		// the digits count the worn slots the way an ItemRef does, anything else cancels
+		if k >= '1' && k <= '8' {
+			item := ui.level.ItemAt(game.ItemRef{In: "worn", Index: int(k - '1')})
+			if item != nil {
+				return &game.Input{Typ: game.UnequipItem, Item: item}
+			}
+		}
+		return nil
+	}
+	switch {
+	case k >= '1' && k <= '9':
+		ui.selected = int(k - '1')
+	case k == 'e' || k == 'E':
+		item := ui.selectedItem()
+		if item != nil {
+			ui.selected = -1
+			return &game.Input{Typ: game.EquipItem, Item: item}
+		}
+	case k == 'r' || k == 'R':
+		ui.takingOff = true
+	case k == 'd' || k == 'D':
+		item := ui.selectedItem()
+		if item != nil {
+			ui.selected = -1
+			return &game.Input{Typ: game.DropItem, Item: item}
+		}
+	case k == 'u' || k == 'U':
+		item := ui.selectedItem()
+		if item != nil {
+			ui.selected = -1
+			return &game.Input{Typ: game.UseItem, Item: item}
+		}
+	case k == 'i' || k == 'I' || k == keyEscape:
+		ui.state = UIMain
+	}
+	return nil
+}
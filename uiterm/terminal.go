@@ -0,0 +1,84 @@
+package uiterm
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+type key rune
+
+// special keys get negative values so they never collide with a typed rune
+const (
+	keyNone key = -iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyEscape
+)
+
+const ctrlC = 3
+
+// stty is used instead of a terminal library so the frontend only needs the standard library
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// puts the terminal in raw mode and returns the previous settings so they can be restored
+func makeRaw() (string, error) {
+	state, err := stty("-g")
+	if err != nil {
+		return "", err
+	}
+	_, err = stty("raw", "-echo")
+	if err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+func restore(state string) {
+	if state != "" {
+		stty(state)
+	}
+}
+
+// reads stdin and turns the raw bytes into keys, arrows arrive as ESC [ A..D
+func readKeys(keys chan<- key) {
+	buf := make([]byte, 16)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		b := buf[:n]
+		for len(b) > 0 {
+			if b[0] == 0x1b {
+				if len(b) >= 3 && (b[1] == '[' || b[1] == 'O') {
+					switch b[2] {
+					case 'A':
+						keys <- keyUp
+					case 'B':
+						keys <- keyDown
+					case 'C':
+						keys <- keyRight
+					case 'D':
+						keys <- keyLeft
+					}
+					b = b[3:]
+					continue
+				}
+				keys <- keyEscape
+				b = b[1:]
+				continue
+			}
+			keys <- key(b[0])
+			b = b[1:]
+		}
+	}
+}
@@ -0,0 +1,337 @@
+// Text frontend for playing over ssh or on machines without SDL2 installed
+package uiterm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/gorillana/rpg/game"
+)
+
+// size of the map viewport in terminal cells
+const (
+	viewWidth  = 60
+	viewHeight = 20
+)
+
+const (
+	ansiReset   = "\x1b[0m"
+	ansiClear   = "\x1b[2J\x1b[H"
+	ansiDim     = "\x1b[2m"
+	ansiBold    = "\x1b[1m"
+	ansiReverse = "\x1b[7m"
+	ansiHide    = "\x1b[?25l"
+	ansiShow    = "\x1b[?25h"
+
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+	colorWhite   = "\x1b[37m"
+	colorGrey    = "\x1b[90m"
+	bgRed        = "\x1b[41m"
+)
+
+type uiState int
+
+const (
+	UIMain uiState = iota
+	UIInventory
+	UIDead
+)
+
+type ui struct {
+	state    uiState
+	selected int
+	// c was pressed next to more than one open door, the next arrow picks which
+	closingDoor bool
+	// r was pressed in the inventory, the next digit picks what to take off
+	takingOff bool
+
+	frame    *game.Frame
+	level    *game.Level
+	keys     chan key
+	ttyState string
+
+	// the game's side of this window, what it follows and the frames it gets
+	view      *game.Window
+	inputChan chan *game.Input
+}
+
+func NewUI(inputChan chan *game.Input, view *game.Window) *ui {
+	ui := &ui{}
+	ui.state = UIMain
+	ui.selected = -1
+	ui.inputChan = inputChan
+	ui.view = view
+	ui.keys = make(chan key, 16)
+	return ui
+}
+
+func tileColor(r rune) string {
+	switch r {
+	case game.StoneWall:
+		return colorWhite
+	case game.DirtFloor:
+		return colorGrey
+	case game.Water:
+		return colorBlue
+	case game.Ice:
+		return colorWhite
+	case game.Lava:
+		return colorRed
+	case game.DeepGrass:
+		return colorGreen
+	case game.CloseDoor, game.OpenDoor, game.WallTorch:
+		return colorYellow
+	case game.UpStair, game.DownStair:
+		return colorMagenta
+	}
+	return ""
+}
+
+// picks what to show in a single map cell, topmost thing first
+func (ui *ui) cell(frame *game.Frame, pos game.Pos) (rune, string) {
+	level := frame.Level
+	tile := level.Map[pos.Y][pos.X]
+	// a revealed view shows everything as if it were in sight
+	if frame.Reveal {
+		tile.Visible = true
+	}
+	if !tile.Visible && !tile.Seen {
+		return ' ', ""
+	}
+	if tile.Visible {
+		if frame.HasPlayer && pos == level.Player.Pos {
+			return level.Player.Rune, ansiBold + colorGreen
+		}
+		monster, exists := level.Monsters[pos]
+		if exists {
+			return monster.Rune, ansiBold + colorRed
+		}
+		items := level.Items[pos]
+		if len(items) > 0 {
+			return items[0].Rune, colorCyan
+		}
+	}
+
+	r := tile.Rune
+	if tile.OverlayRune != game.Blank {
+		r = tile.OverlayRune
+	}
+	if r == game.Blank {
+		return ' ', ""
+	}
+	color := tileColor(r)
+	if level.Debug[pos] {
+		color = bgRed + color
+	}
+	if !tile.Visible {
+		// seen but out of sight, drawn dimmed
+		color = ansiDim + colorGrey
+	} else if level.LightAt(pos) <= game.MaxLight/2 {
+		// in sight but hardly lit
+		color = ansiDim + color
+	}
+	return r, color
+}
+
+func (ui *ui) Draw(frame *game.Frame) {
+	level := frame.Level
+	var b bytes.Buffer
+	b.WriteString(ansiClear)
+
+	// keep whatever the window follows in the middle of the viewport
+	startX := frame.Center.X - viewWidth/2
+	startY := frame.Center.Y - viewHeight/2
+
+	for y := startY; y < startY+viewHeight; y++ {
+		for x := startX; x < startX+viewWidth; x++ {
+			if y < 0 || y >= len(level.Map) || x < 0 || x >= len(level.Map[y]) {
+				b.WriteByte(' ')
+				continue
+			}
+			r, color := ui.cell(frame, game.Pos{X: x, Y: y})
+			if color != "" {
+				b.WriteString(color)
+				b.WriteRune(r)
+				b.WriteString(ansiReset)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+		b.WriteString("\r\n")
+	}
+
+	p := level.Player
+	stats := p.Stats()
+	b.WriteString(ansiBold + p.Name + ansiReset + "  HP: " + strconv.Itoa(p.Hitpoints) + "/" + strconv.Itoa(stats.MaxHitpoints) +
+		"  Atk: " + strconv.Itoa(stats.Attack) + "  Def: " + strconv.Itoa(stats.Defense) + "\r\n")
+
+	items := level.Items[p.Pos]
+	if len(items) > 0 && frame.HasPlayer {
+		b.WriteString("Here:")
+		for _, item := range items {
+			b.WriteString(" " + colorCyan + item.Name + ansiReset)
+		}
+		b.WriteString("  (T to take all)")
+	}
+	b.WriteString("\r\n")
+
+	// how the last turn's swings went
+	for _, event := range level.TurnEvents {
+		switch event.Kind {
+		case game.Miss:
+			b.WriteString(colorGrey + event.Actor + ": miss" + ansiReset + "  ")
+		case game.Crit:
+			b.WriteString(ansiBold + colorYellow + event.Actor + ": crit " + strconv.Itoa(event.Amount) + ansiReset + "  ")
+		case game.Hit:
+			b.WriteString(colorRed + event.Actor + ": " + strconv.Itoa(event.Amount) + ansiReset + "  ")
+		case game.Burned:
+			b.WriteString(colorRed + event.Actor + ": burn " + strconv.Itoa(event.Amount) + ansiReset + "  ")
+		}
+	}
+	b.WriteString("\r\n")
+
+	// events are a ring buffer, oldest first starting at EventPos
+	i := level.EventPos
+	for {
+		event := level.Events[i]
+		if event != "" {
+			b.WriteString(colorRed + event + ansiReset + "\r\n")
+		}
+		i = (i + 1) % len(level.Events)
+		if i == level.EventPos {
+			break
+		}
+	}
+
+	if ui.state == UIDead {
+		b.WriteString("\r\n" + ansiBold + colorRed + "You have died" + ansiReset + "  r: restart  q: quit\r\n")
+	} else if ui.state == UIInventory {
+		ui.drawInventory(&b, level)
+	} else {
+		if ui.closingDoor {
+			b.WriteString(ansiBold + "Close which door? a direction picks, any other key cancels" + ansiReset + "\r\n")
+		} else {
+			b.WriteString(colorGrey + "arrows/hjklyubn/numpad: move  t: take all  c: close door  i: inventory  q: quit" + ansiReset + "\r\n")
+		}
+	}
+
+	os.Stdout.Write(b.Bytes())
+}
+
+// arrows, vi keys and the numpad digits with num lock on, the diagonals only do
+// anything when the game allows them
+var directionKeys = map[key]game.Pos{
+	keyUp:    {X: 0, Y: -1},
+	keyDown:  {X: 0, Y: 1},
+	keyLeft:  {X: -1, Y: 0},
+	keyRight: {X: 1, Y: 0},
+	'k':      {X: 0, Y: -1},
+	'j':      {X: 0, Y: 1},
+	'h':      {X: -1, Y: 0},
+	'l':      {X: 1, Y: 0},
+	'y':      {X: -1, Y: -1},
+	'u':      {X: 1, Y: -1},
+	'b':      {X: -1, Y: 1},
+	'n':      {X: 1, Y: 1},
+	'8':      {X: 0, Y: -1},
+	'2':      {X: 0, Y: 1},
+	'4':      {X: -1, Y: 0},
+	'6':      {X: 1, Y: 0},
+	'7':      {X: -1, Y: -1},
+	'9':      {X: 1, Y: -1},
+	'1':      {X: -1, Y: 1},
+	'3':      {X: 1, Y: 1},
+}
+
+// translates a key into game input, returns nil if the key only changes ui state
+func (ui *ui) handleKey(k key) *game.Input {
+	if ui.state == UIDead {
+		if k == 'r' || k == 'R' {
+			ui.state = UIMain
+			return &game.Input{Typ: game.Restart}
+		}
+		return nil
+	}
+	if ui.state == UIInventory {
+		return ui.handleInventoryKey(k)
+	}
+	if ui.closingDoor {
+		ui.closingDoor = false
+		p := ui.level.Player.Pos
+		if dir, ok := directionKeys[k]; ok {
+			return &game.Input{Typ: game.ShutDoor, Pos: game.Pos{X: p.X + dir.X, Y: p.Y + dir.Y}}
+		}
+		// anything else cancels
+		return nil
+	}
+	if dir, ok := directionKeys[k]; ok {
+		return &game.Input{Typ: game.MoveInput(dir)}
+	}
+	switch k {
+	case 't', 'T':
+		return &game.Input{Typ: game.TakeAll}
+	case 'c', 'C':
+		doors := ui.level.OpenDoorsNear(ui.level.Player.Pos)
+		if len(doors) == 1 {
+			return &game.Input{Typ: game.ShutDoor, Pos: doors[0]}
+		}
+		ui.closingDoor = len(doors) > 1
+	case 'i', 'I':
+		ui.state = UIInventory
+		ui.selected = -1
+	}
+	return nil
+}
+
+func (ui *ui) quit() {
+	os.Stdout.WriteString(ansiReset + ansiShow + "\r\n")
+	restore(ui.ttyState)
+}
+
+func (ui *ui) Run() {
+	state, err := makeRaw()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "couldn't put terminal in raw mode:", err)
+	}
+	ui.ttyState = state
+	os.Stdout.WriteString(ansiHide)
+	go readKeys(ui.keys)
+
+	for {
+		select {
+		case frame, ok := <-ui.view.Frames():
+			if !ok {
+				ui.quit()
+				return
+			}
+			ui.frame, ui.level = frame, frame.Level
+			if frame.HasPlayer && frame.Level.Happened(game.Death) {
+				ui.state = UIDead
+			}
+			ui.Draw(frame)
+		case k, ok := <-ui.keys:
+			if !ok || k == 'q' || k == 'Q' || k == ctrlC {
+				// restore the terminal before the game loop exits the process
+				ui.quit()
+				ui.inputChan <- &game.Input{Typ: game.QuitGame}
+				return
+			}
+			if ui.level == nil {
+				continue
+			}
+			input := ui.handleKey(k)
+			if input != nil {
+				ui.inputChan <- input
+			} else {
+				ui.Draw(ui.frame)
+			}
+		}
+	}
+}